@@ -0,0 +1,100 @@
+package ansi
+
+import (
+	"io"
+	"strings"
+)
+
+const escape = 0x1b
+
+type state int
+
+const (
+	text state = iota
+	// after ESC
+	escaped
+	// after ESC followed by intermediate bytes, as in ESC ( B
+	intermediate
+	// in a control sequence, ESC [ ... final byte
+	control
+	// in an operating system command, ESC ] ... BEL or ESC \
+	command
+	// after ESC in an operating system command
+	commandEscaped
+)
+
+// Writer removes ANSI escape sequences, such as colors and cursor movement,
+// from what is written through it. Sequences may be split across writes.
+type Writer struct {
+	out   io.Writer
+	state state
+}
+
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{out: out}
+}
+
+// Strip removes ANSI escape sequences from s.
+func Strip(s string) string {
+	stripped := &strings.Builder{}
+	NewWriter(stripped).Write([]byte(s))
+	return stripped.String()
+}
+
+func next(current state, b byte) (state, bool) {
+	switch current {
+	case escaped:
+		switch {
+		case b == '[':
+			return control, false
+		case b == ']':
+			return command, false
+		case b >= 0x20 && b <= 0x2f:
+			return intermediate, false
+		default:
+			return text, false
+		}
+	case intermediate:
+		if b >= 0x20 && b <= 0x2f {
+			return intermediate, false
+		}
+		return text, false
+	case control:
+		if b >= 0x40 && b <= 0x7e {
+			return text, false
+		}
+		return control, false
+	case command:
+		switch b {
+		case 0x07:
+			return text, false
+		case escape:
+			return commandEscaped, false
+		}
+		return command, false
+	case commandEscaped:
+		if b == '\\' {
+			return text, false
+		}
+		return command, false
+	}
+	if b == escape {
+		return escaped, false
+	}
+	return text, true
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	stripped := make([]byte, 0, len(p))
+	for _, b := range p {
+		var keep bool
+		w.state, keep = next(w.state, b)
+		if keep {
+			stripped = append(stripped, b)
+		}
+	}
+	if _, err := w.out.Write(stripped); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,34 @@
+package ansi_test
+
+import (
+	"bytes"
+
+	"github.com/cf-platform-eng/mrlog/ansi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ANSI", func() {
+	It("removes colors, cursor movement and terminal titles", func() {
+		Expect(ansi.Strip("\x1b[1;31mFAIL\x1b[0m: test")).To(Equal("FAIL: test"))
+		Expect(ansi.Strip("50%\x1b[2K\r\x1b[1A100%")).To(Equal("50%\r100%"))
+		Expect(ansi.Strip("\x1b]0;build\x07done")).To(Equal("done"))
+		Expect(ansi.Strip("\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\")).To(Equal("link"))
+		Expect(ansi.Strip("\x1b(Bplain \x1b=text")).To(Equal("plain text"))
+	})
+
+	It("leaves other text untouched", func() {
+		Expect(ansi.Strip("plain text ✓ [ok]\n")).To(Equal("plain text ✓ [ok]\n"))
+	})
+
+	It("handles sequences split across writes", func() {
+		out := &bytes.Buffer{}
+		writer := ansi.NewWriter(out)
+		for _, chunk := range []string{"\x1b", "[3", "2mok\x1b[", "0m\n"} {
+			n, err := writer.Write([]byte(chunk))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(len(chunk)))
+		}
+		Expect(out.String()).To(Equal("ok\n"))
+	})
+})
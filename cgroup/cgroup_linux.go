@@ -0,0 +1,40 @@
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// New creates a group named name below the group of the mrlog process.
+// This needs write access to that group, for example through systemd
+// delegation.
+func New(name string) (*Group, error) {
+	procCgroup, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return nil, err
+	}
+	path, err := OwnPath(string(procCgroup))
+	if err != nil {
+		return nil, err
+	}
+	mountInfo, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	root, err := MountPoint(string(mountInfo))
+	if err != nil {
+		return nil, err
+	}
+
+	group := &Group{Path: filepath.Join(root, path, name)}
+	if err := os.Mkdir(group.Path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	group.dir, err = os.Open(group.Path)
+	if err != nil { // !branch-not-tested
+		os.Remove(group.Path)
+		return nil, fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	return group, nil
+}
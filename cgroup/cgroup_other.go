@@ -0,0 +1,9 @@
+//go:build !linux
+
+package cgroup
+
+import "errors"
+
+func New(name string) (*Group, error) {
+	return nil, errors.New("cgroups are only supported on Linux")
+}
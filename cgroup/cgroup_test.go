@@ -0,0 +1,73 @@
+package cgroup_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cf-platform-eng/mrlog/cgroup"
+	"github.com/cf-platform-eng/mrlog/mrl"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cgroup", func() {
+	Describe("Usage", func() {
+		var group *cgroup.Group
+
+		BeforeEach(func() {
+			dir, err := os.MkdirTemp("", "mrlog-cgroup")
+			Expect(err).NotTo(HaveOccurred())
+			group = &cgroup.Group{Path: dir}
+			Expect(os.WriteFile(filepath.Join(dir, "cpu.stat"), []byte("usage_usec 1500000\nuser_usec 1200000\nsystem_usec 300000\n"), 0644)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(group.Path)).To(Succeed())
+		})
+
+		It("reads the cpu, memory and io statistics", func() {
+			Expect(os.WriteFile(filepath.Join(group.Path, "memory.peak"), []byte("47185920\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(group.Path, "io.stat"), []byte(
+				"8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n"+
+					"8:16 rbytes=1024 wbytes=0 rios=1 wios=0 dbytes=0 dios=0\n"), 0644)).To(Succeed())
+
+			Expect(group.Usage()).To(Equal(&mrl.CgroupUsage{
+				UserCPU:      1.2,
+				SystemCPU:    0.3,
+				MemoryPeak:   47185920,
+				IOReadBytes:  5120,
+				IOWriteBytes: 8192,
+			}))
+		})
+
+		It("skips controllers that are not enabled", func() {
+			Expect(group.Usage()).To(Equal(&mrl.CgroupUsage{UserCPU: 1.2, SystemCPU: 0.3}))
+		})
+
+		It("fails without cpu statistics", func() {
+			Expect(os.Remove(filepath.Join(group.Path, "cpu.stat"))).To(Succeed())
+			_, err := group.Usage()
+			Expect(err).To(MatchError(ContainSubstring("failed to read cgroup cpu usage")))
+		})
+
+		It("fails on invalid statistics", func() {
+			Expect(os.WriteFile(filepath.Join(group.Path, "memory.peak"), []byte("max\n"), 0644)).To(Succeed())
+			_, err := group.Usage()
+			Expect(err).To(MatchError(ContainSubstring("invalid memory.peak")))
+		})
+	})
+
+	It("finds the cgroup v2 path of the process", func() {
+		Expect(cgroup.OwnPath("4:memory:/a\n0::/user.slice/session-1.scope\n")).To(Equal("/user.slice/session-1.scope"))
+		_, err := cgroup.OwnPath("4:memory:/a\n")
+		Expect(err).To(MatchError("not in a cgroup v2 group"))
+	})
+
+	It("finds the cgroup v2 mount point", func() {
+		mountInfo := "30 23 0:26 / /sys/fs/cgroup/memory rw,relatime shared:9 - cgroup cgroup rw,memory\n" +
+			"35 23 0:31 / /sys/fs/cgroup/unified rw,relatime shared:14 - cgroup2 cgroup2 rw\n"
+		Expect(cgroup.MountPoint(mountInfo)).To(Equal("/sys/fs/cgroup/unified"))
+		_, err := cgroup.MountPoint("30 23 0:26 / /sys/fs/cgroup/memory rw - cgroup cgroup rw\n")
+		Expect(err).To(MatchError("cgroup v2 is not mounted"))
+	})
+})
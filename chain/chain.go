@@ -0,0 +1,113 @@
+package chain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/cf-platform-eng/mrlog/debug"
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+// Hash returns the hex encoded sha256 of the canonical form of a record.
+func Hash(record []byte) (string, error) {
+	canonical, err := mrl.Canonical(record)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type state struct {
+	Seq  int64  `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// Chain links every record to the previous one with a sequence number and
+// the hash of the previous record. The position in the chain is kept in a
+// state file, locked while a record is linked so that concurrent mrlog
+// processes extend the chain one at a time.
+type Chain struct {
+	StatePath string
+	Debug     *debug.Logger
+}
+
+func (c *Chain) Link(record *mrl.MachineReadableLog, encode func() ([]byte, error)) ([]byte, error) {
+	file, err := os.OpenFile(c.StatePath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chain state: %w", err)
+	}
+	defer file.Close()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil { // !branch-not-tested
+		return nil, fmt.Errorf("failed to lock chain state: %w", err)
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	previous, err := readState(file)
+	if err != nil {
+		return nil, err
+	}
+	c.Debug.Printf("chain state %s: read seq %d hash %s", c.StatePath, previous.Seq, previous.Hash)
+
+	record.Seq = previous.Seq + 1
+	record.PrevHash = previous.Hash
+	recordJSON, err := encode()
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := Hash(recordJSON)
+	if err != nil { // !branch-not-tested
+		return nil, err
+	}
+	if err := writeState(file, state{Seq: record.Seq, Hash: hash}); err != nil { // !branch-not-tested
+		return nil, fmt.Errorf("failed to write chain state: %w", err)
+	}
+	c.Debug.Printf("chain state %s: wrote seq %d hash %s", c.StatePath, record.Seq, hash)
+	return recordJSON, nil
+}
+
+func readState(file *os.File) (state, error) {
+	current := state{}
+	contents, err := io.ReadAll(file)
+	if err != nil { // !branch-not-tested
+		return current, fmt.Errorf("failed to read chain state: %w", err)
+	}
+	if len(bytes.TrimSpace(contents)) == 0 {
+		return current, nil
+	}
+	if err := json.Unmarshal(contents, &current); err != nil {
+		return current, fmt.Errorf("invalid chain state: %w", err)
+	}
+	return current, nil
+}
+
+func ReadState(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open chain state: %w", err)
+	}
+	defer file.Close()
+
+	current, err := readState(file)
+	return current.Seq, current.Hash, err
+}
+
+func writeState(file *os.File, current state) error {
+	contents, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err = file.WriteAt(contents, 0)
+	return err
+}
@@ -0,0 +1,174 @@
+package chain_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/chain"
+	"github.com/cf-platform-eng/mrlog/debug"
+	"github.com/cf-platform-eng/mrlog/mrl"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Chain", func() {
+	var (
+		dir       string
+		statePath string
+		logPath   string
+		log       *Buffer
+		printer   *mrl.Printer
+	)
+
+	printRecord := func(name string) {
+		record := &mrl.MachineReadableLog{
+			Type: "section-start",
+			Name: name,
+			Time: time.Date(1973, 11, 29, 10, 15, 01, 00, time.UTC),
+		}
+		Expect(printer.Print("section-start: '"+name+"'", record, "\n")).To(Succeed())
+	}
+
+	lines := func() []string {
+		return strings.Split(strings.TrimSuffix(string(log.Contents()), "\n"), "\n")
+	}
+
+	writeLog := func(lines []string) {
+		Expect(os.WriteFile(logPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)).To(Succeed())
+	}
+
+	verify := func() (*Buffer, error) {
+		out := NewBuffer()
+		opts := &chain.VerifyChainOpt{StatePath: statePath, Out: out}
+		return out, opts.Execute([]string{logPath})
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "mrlog-chain")
+		Expect(err).NotTo(HaveOccurred())
+		statePath = filepath.Join(dir, "chain.state")
+		logPath = filepath.Join(dir, "build.log")
+
+		log = NewBuffer()
+		printer = &mrl.Printer{
+			Out:   log,
+			Chain: &chain.Chain{StatePath: statePath},
+		}
+		printRecord("one")
+		printRecord("two")
+		printRecord("three")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("links records with a sequence number and the previous hash", func() {
+		Expect(lines()[0]).To(HaveSuffix(`"seq":1}`))
+		Expect(lines()[1]).To(MatchRegexp(`"seq":2,"prev_hash":"[0-9a-f]{64}"}$`))
+
+		first, _ := mrl.Extract(lines()[0])
+		hash, err := chain.Hash([]byte(first))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lines()[1]).To(ContainSubstring(hash))
+
+		seq, lastHash, err := chain.ReadState(statePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(seq).To(Equal(int64(3)))
+		Expect(lastHash).To(HaveLen(64))
+	})
+
+	It("logs state reads and writes when debugging", func() {
+		debugOut := NewBuffer()
+		printer.Chain = &chain.Chain{StatePath: statePath, Debug: &debug.Logger{Out: debugOut}}
+		printRecord("four")
+
+		Expect(debugOut).To(Say(`chain state .*chain.state: read seq 3 hash [0-9a-f]{64}`))
+		Expect(debugOut).To(Say(`chain state .*chain.state: wrote seq 4 hash [0-9a-f]{64}`))
+	})
+
+	It("verifies an untouched log, ignoring other output", func() {
+		writeLog(append([]string{"some output"}, lines()...))
+		out, err := verify()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Say("chain verified: 3 records"))
+	})
+
+	It("ignores output that looks like JSON", func() {
+		writeLog([]string{lines()[0], "{", `"a":1`, "}", `{"a":1}`, lines()[1], lines()[2]})
+		out, err := verify()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Say("chain verified: 3 records"))
+	})
+
+	It("detects invalid records", func() {
+		writeLog([]string{lines()[0], "broken MRL:{", lines()[1], lines()[2]})
+		_, err := verify()
+		Expect(err).To(MatchError(HavePrefix("chain broken at line 2 (seq 0): invalid record:")))
+	})
+
+	It("detects edits", func() {
+		edited := lines()
+		edited[0] = strings.Replace(edited[0], `"name":"one"`, `"name":"uno"`, 1)
+		writeLog(edited)
+		_, err := verify()
+		Expect(err).To(MatchError("chain broken at line 2 (seq 2): prev_hash does not match the previous record, which was edited"))
+	})
+
+	It("detects deletions", func() {
+		writeLog([]string{lines()[0], lines()[2]})
+		_, err := verify()
+		Expect(err).To(MatchError("chain broken at line 2 (seq 3): expected seq 2, records were inserted or deleted"))
+	})
+
+	It("detects insertions", func() {
+		writeLog([]string{lines()[0], lines()[1], lines()[1], lines()[2]})
+		_, err := verify()
+		Expect(err).To(MatchError("chain broken at line 3 (seq 2): expected seq 3, records were inserted or deleted"))
+	})
+
+	It("ignores records written before chaining was enabled", func() {
+		unchained := `section-start: 'zero' MRL:{"type":"section-start","name":"zero","time":"1973-11-29T10:15:01Z"}`
+		writeLog(append([]string{unchained}, lines()...))
+		out, err := verify()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Say("chain verified: 3 records"))
+	})
+
+	It("detects inserted records without a seq", func() {
+		forged := `forged MRL:{"type":"section-end","name":"one","time":"1973-11-29T10:15:01Z"}`
+		writeLog([]string{lines()[0], forged, lines()[1], lines()[2]})
+		_, err := verify()
+		Expect(err).To(MatchError("chain broken at line 2 (seq 0): record without seq within the chain, which was inserted or edited"))
+	})
+
+	It("detects a seq removed from a record", func() {
+		edited := lines()
+		edited[2] = regexp.MustCompile(`,"seq":3,"prev_hash":"[0-9a-f]+"`).ReplaceAllString(edited[2], "")
+		writeLog(edited)
+		_, err := verify()
+		Expect(err).To(MatchError("chain broken at line 3 (seq 0): record without seq within the chain, which was inserted or edited"))
+	})
+
+	It("detects edits to the last record using the state file", func() {
+		edited := lines()
+		edited[2] = strings.Replace(edited[2], `"name":"three"`, `"name":"tres"`, 1)
+		writeLog(edited)
+		_, err := verify()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("the last records were edited or removed"))
+	})
+
+	It("ignores formatting differences in the records", func() {
+		reformatted := lines()
+		reformatted[0] = strings.Replace(reformatted[0], `{"type":"section-start",`, `{ "type": "section-start", `, 1)
+		writeLog(reformatted)
+		_, err := verify()
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,135 @@
+package chain
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+type VerifyChainOpt struct {
+	StatePath string `long:"state" env:"MRLOG_CHAIN_STATE" description:"chain state file to also check the last record against"`
+	Out       io.Writer
+}
+
+// BrokenLinkError describes the first record that does not follow from the
+// previous one.
+type BrokenLinkError struct {
+	Line   int
+	Seq    int64
+	Reason string
+}
+
+func (e *BrokenLinkError) Error() string {
+	return fmt.Sprintf("chain broken at line %d (seq %d): %s", e.Line, e.Seq, e.Reason)
+}
+
+type link struct {
+	Seq      int64  `json:"seq"`
+	PrevHash string `json:"prev_hash"`
+}
+
+// Verify checks that the chained records in a log follow each other without
+// insertions, deletions or edits. It returns the number of records checked,
+// and the sequence number and hash of the last one.
+func Verify(in io.Reader) (int64, string, error) {
+	var (
+		expectedSeq int64 = 1
+		lastHash    string
+		count       int64
+		line        int
+	)
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line++
+		// bare JSON output of subcommands is not a record
+		if _, ok, err := mrl.Parse(scanner.Text()); err != nil {
+			return count, lastHash, &BrokenLinkError{Line: line, Reason: fmt.Sprintf("invalid record: %s", err)}
+		} else if !ok {
+			continue
+		}
+		record, _ := mrl.Extract(scanner.Text())
+
+		current := link{}
+		if err := json.Unmarshal([]byte(record), &current); err != nil { // !branch-not-tested
+			return count, lastHash, &BrokenLinkError{Line: line, Reason: fmt.Sprintf("invalid record: %s", err)}
+		}
+		if current.Seq == 0 {
+			if count == 0 {
+				// records written before chaining was enabled are not part
+				// of the chain
+				continue
+			}
+			return count, lastHash, &BrokenLinkError{
+				Line:   line,
+				Reason: "record without seq within the chain, which was inserted or edited",
+			}
+		}
+
+		if current.Seq != expectedSeq {
+			return count, lastHash, &BrokenLinkError{
+				Line:   line,
+				Seq:    current.Seq,
+				Reason: fmt.Sprintf("expected seq %d, records were inserted or deleted", expectedSeq),
+			}
+		}
+		if current.PrevHash != lastHash {
+			return count, lastHash, &BrokenLinkError{
+				Line:   line,
+				Seq:    current.Seq,
+				Reason: "prev_hash does not match the previous record, which was edited",
+			}
+		}
+
+		hash, err := Hash([]byte(record))
+		if err != nil { // !branch-not-tested
+			return count, lastHash, &BrokenLinkError{Line: line, Seq: current.Seq, Reason: err.Error()}
+		}
+		lastHash = hash
+		expectedSeq++
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return count, lastHash, fmt.Errorf("failed to read log: %w", err)
+	}
+	return count, lastHash, nil
+}
+
+func (opts *VerifyChainOpt) Execute(args []string) error {
+	if len(args) != 1 {
+		return errors.New("verify-chain requires a log file argument")
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+	defer file.Close()
+
+	count, lastHash, err := Verify(file)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("no chained records found")
+	}
+
+	if opts.StatePath != "" {
+		seq, hash, err := ReadState(opts.StatePath)
+		if err != nil {
+			return err
+		}
+		if seq != count || hash != lastHash {
+			return fmt.Errorf("chain state is at seq %d but the log ends at seq %d with a different hash: the last records were edited or removed", seq, count)
+		}
+	}
+
+	_, err = fmt.Fprintf(opts.Out, "chain verified: %d records\n", count)
+	return err
+}
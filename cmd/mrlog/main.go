@@ -1,13 +1,29 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/cf-platform-eng/mrlog"
+	"github.com/cf-platform-eng/mrlog/chain"
+	"github.com/cf-platform-eng/mrlog/configfile"
+	"github.com/cf-platform-eng/mrlog/convert"
+	"github.com/cf-platform-eng/mrlog/debug"
 	"github.com/cf-platform-eng/mrlog/dependency"
+	"github.com/cf-platform-eng/mrlog/environment"
+	"github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/note"
+	"github.com/cf-platform-eng/mrlog/parallel"
+	"github.com/cf-platform-eng/mrlog/redact"
+	"github.com/cf-platform-eng/mrlog/run"
 	"github.com/cf-platform-eng/mrlog/section"
+	"github.com/cf-platform-eng/mrlog/signing"
+	"github.com/cf-platform-eng/mrlog/sink"
+	"github.com/cf-platform-eng/mrlog/steps"
+	"github.com/cf-platform-eng/mrlog/trace"
 	"github.com/jessevdk/go-flags"
 
 	"github.com/cf-platform-eng/mrlog/version"
@@ -15,16 +31,60 @@ import (
 
 var config mrlog.Config
 var parser = flags.NewParser(&config, flags.Default)
+var printer = &mrl.Printer{
+	Out: os.Stdout,
+	Err: os.Stderr,
+}
+var configFile = &configfile.Config{}
+var debugLog = &debug.Logger{Start: time.Now()}
+var tracer = &trace.Tracer{
+	Parent: os.Getenv(trace.TraceParentEnv),
+	Warn:   os.Stderr,
+}
 
 func main() {
+	parser.CommandHandler = func(command flags.Commander, args []string) error {
+		if command == nil {
+			return nil
+		}
+		cwd, err := os.Getwd()
+		if err != nil { // !branch-not-tested
+			return err
+		}
+		if err := configFile.Load(configfile.Paths(cwd, os.Getenv)); err != nil {
+			return err
+		}
+		if err := configFile.Apply(parser); err != nil {
+			return err
+		}
+		if config.Debug {
+			debugLog.Out = os.Stderr
+			debugConfig()
+		}
+
+		done := debugLog.Time("configure")
+		if err := configure(); err != nil {
+			return err
+		}
+		done()
+		defer func() {
+			done := debugLog.Time("closing sinks")
+			printer.Close()
+			done()
+		}()
+
+		defer debugLog.Time(configfile.Active(parser).Name)()
+		return command.Execute(args)
+	}
 
 	_, err := parser.AddCommand(
 		"dependency",
 		"log a dependecy",
 		"log a dependency in MRL format",
 		&dependency.DependencyOpt{
-			Out:   os.Stdout,
-			Clock: &mrlog.Clock{},
+			Printer: printer,
+			Clock:   &mrlog.Clock{},
+			Tracer:  tracer,
 		},
 	)
 	if err != nil {
@@ -40,8 +100,8 @@ func main() {
 			Section: section.Section{
 				Type: "start",
 			},
-			Out:   os.Stdout,
-			Clock: &mrlog.Clock{},
+			Printer: printer,
+			Clock:   &mrlog.Clock{},
 		},
 	)
 	if err != nil {
@@ -57,8 +117,25 @@ func main() {
 			Section: section.Section{
 				Type: "end",
 			},
-			Out:   os.Stdout,
-			Clock: &mrlog.Clock{},
+			Printer: printer,
+			Clock:   &mrlog.Clock{},
+		},
+	)
+	if err != nil {
+		fmt.Println("Could not add section command")
+		os.Exit(1)
+	}
+
+	_, err = parser.AddCommand(
+		"section-skip",
+		"log a skipped section",
+		"log a section that was skipped, and why, in MRL format",
+		&section.SectionOpt{
+			Section: section.Section{
+				Type: "skip",
+			},
+			Printer: printer,
+			Clock:   &mrlog.Clock{},
 		},
 	)
 	if err != nil {
@@ -74,9 +151,11 @@ func main() {
 			Section: section.Section{
 				Type: "section",
 			},
-			Out:   os.Stdout,
-			Clock: &mrlog.Clock{},
-			Exec:  &mrlog.Exec{},
+			Printer: printer,
+			Clock:   &mrlog.Clock{},
+			Exec:    &mrlog.Exec{},
+			Tracer:  tracer,
+			Debug:   debugLog,
 		},
 	)
 	if err != nil {
@@ -84,6 +163,175 @@ func main() {
 		os.Exit(1)
 	}
 
+	_, err = parser.AddCommand(
+		"parallel",
+		"run tasks in parallel as sections",
+		"run tasks concurrently, each in a section of its own, within a section that fails if any task fails",
+		&parallel.ParallelOpt{
+			Printer: printer,
+			Clock:   &mrlog.Clock{},
+			Exec:    &mrlog.Exec{},
+			Tracer:  tracer,
+			Debug:   debugLog,
+		},
+	)
+	if err != nil {
+		fmt.Println("Could not add parallel command")
+		os.Exit(1)
+	}
+
+	_, err = parser.AddCommand(
+		"run",
+		"run a step file",
+		"run the steps of a YAML step file in order, each as a section, within a section that fails if a step fails",
+		&steps.RunOpt{
+			Printer: printer,
+			Clock:   &mrlog.Clock{},
+			Exec:    &mrlog.Exec{},
+			Tracer:  tracer,
+			Debug:   debugLog,
+		},
+	)
+	if err != nil {
+		fmt.Println("Could not add run command")
+		os.Exit(1)
+	}
+
+	_, err = parser.AddCommand(
+		"note",
+		"log a note",
+		"log a free-form message in MRL format",
+		&note.NoteOpt{
+			Printer: printer,
+			Clock:   &mrlog.Clock{},
+		},
+	)
+	if err != nil {
+		fmt.Println("Could not add note command")
+		os.Exit(1)
+	}
+
+	_, err = parser.AddCommand(
+		"run-start",
+		"start a run",
+		"generate a run ID and print a shell statement exporting it as MRLOG_RUN_ID, for use with eval",
+		&run.RunStartOpt{
+			Printer: printer,
+			Clock:   &mrlog.Clock{},
+		},
+	)
+	if err != nil {
+		fmt.Println("Could not add run-start command")
+		os.Exit(1)
+	}
+
+	_, err = parser.AddCommand(
+		"env",
+		"log the execution environment",
+		"log the operating system, hardware, container and allowed environment variables in MRL format",
+		&environment.EnvOpt{
+			Printer: printer,
+			Clock:   &mrlog.Clock{},
+			System:  &environment.Host{},
+		},
+	)
+	if err != nil {
+		fmt.Println("Could not add env command")
+		os.Exit(1)
+	}
+
+	_, err = parser.AddCommand(
+		"convert",
+		"convert an MRL log",
+		"read an MRL log from stdin and convert it to another format",
+		&convert.ConvertOpt{
+			In:  os.Stdin,
+			Out: os.Stdout,
+		},
+	)
+	if err != nil {
+		fmt.Println("Could not add convert command")
+		os.Exit(1)
+	}
+
+	_, err = parser.AddCommand(
+		"verify-chain",
+		"verify a hash chained log",
+		"verify that the hash chained MRL records in a log file were not inserted, deleted or edited",
+		&chain.VerifyChainOpt{
+			Out: os.Stdout,
+		},
+	)
+	if err != nil {
+		fmt.Println("Could not add verify-chain command")
+		os.Exit(1)
+	}
+
+	_, err = parser.AddCommand(
+		"keygen",
+		"generate a signing key",
+		"generate an Ed25519 key pair for signing logs",
+		&signing.KeygenOpt{
+			Out: os.Stdout,
+		},
+	)
+	if err != nil {
+		fmt.Println("Could not add keygen command")
+		os.Exit(1)
+	}
+
+	_, err = parser.AddCommand(
+		"sign",
+		"sign a log",
+		"write a detached Ed25519 signature over the MRL records in a log file, ignoring human readable text",
+		&signing.SignOpt{
+			Out: os.Stdout,
+		},
+	)
+	if err != nil {
+		fmt.Println("Could not add sign command")
+		os.Exit(1)
+	}
+
+	_, err = parser.AddCommand(
+		"verify",
+		"verify a signed log",
+		"verify the detached signature of a log file and the signature of every signed record in it",
+		&signing.VerifyOpt{
+			Out: os.Stdout,
+		},
+	)
+	if err != nil {
+		fmt.Println("Could not add verify command")
+		os.Exit(1)
+	}
+
+	configCommand, err := parser.AddCommand(
+		"config",
+		"inspect the configuration",
+		"inspect the configuration read from .mrlog.yml, $XDG_CONFIG_HOME/mrlog/config.yml and MRLOG_* environment variables",
+		&struct{}{},
+	)
+	if err != nil {
+		fmt.Println("Could not add config command")
+		os.Exit(1)
+	}
+
+	_, err = configCommand.AddCommand(
+		"show",
+		"print the effective configuration",
+		"print the effective value of every option and where it came from",
+		&configfile.ShowOpt{
+			Parser: parser,
+			Config: configFile,
+			Out:    os.Stdout,
+		},
+	)
+	if err != nil {
+		fmt.Println("Could not add config show command")
+		os.Exit(1)
+	}
+
 	_, err = parser.AddCommand(
 		"version",
 		"print version",
@@ -105,3 +353,73 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// debugConfig logs the configuration files and the resolved options
+func debugConfig() {
+	resolved := &bytes.Buffer{}
+	show := &configfile.ShowOpt{Parser: parser, Config: configFile, Out: resolved}
+	if err := show.Execute(nil); err != nil {
+		debugLog.Printf("failed to resolve config: %s", err)
+		return
+	}
+	debugLog.Printf("%s", resolved.String())
+}
+
+// configure applies the global options to the shared printer and tracer
+func configure() error {
+	printer.Format = mrl.Format(config.Format)
+	printer.Run = run.Detect(os.Getenv)
+	if printer.Run != nil {
+		debugLog.Printf("run %s, provider '%s'", printer.Run.ID, printer.Run.Provider)
+	}
+
+	var secrets []string
+	for _, name := range config.RedactEnv {
+		secrets = append(secrets, os.Getenv(name))
+	}
+	redactor, err := redact.New(secrets, config.RedactRegex, !config.NoRedactDetectors)
+	if err != nil {
+		return err
+	}
+	printer.Redactor = redactor
+
+	if config.SignKey != "" {
+		key, err := signing.LoadPrivateKey(config.SignKey)
+		if err != nil {
+			return err
+		}
+		printer.Signer = key
+	}
+	if config.ChainState != "" {
+		printer.Chain = &chain.Chain{StatePath: config.ChainState, Debug: debugLog}
+	}
+
+	if config.MRLFile != "" {
+		printer.Sinks = append(printer.Sinks, &sink.File{Path: config.MRLFile})
+	}
+	spoolDir := config.SpoolDir
+	if spoolDir == "" {
+		spoolDir = sink.DefaultSpoolDir()
+	}
+	for _, sinkURL := range config.Sinks {
+		forwarder, err := sink.NewForwarder(sinkURL, spoolDir, os.Stderr)
+		if err != nil {
+			return err
+		}
+		forwarder.Debug = debugLog
+		printer.Sinks = append(printer.Sinks, forwarder)
+	}
+
+	if config.OTLPEndpoint != "" {
+		serviceName := os.Getenv("OTEL_SERVICE_NAME")
+		if serviceName == "" {
+			serviceName = mrlog.APP_NAME
+		}
+		tracer.Exporter = &trace.Exporter{
+			Endpoint:    config.OTLPEndpoint,
+			ServiceName: serviceName,
+			Version:     version.Version,
+		}
+	}
+	return nil
+}
@@ -2,6 +2,16 @@ package mrlog
 
 type (
 	Config struct {
-		Debug bool `long:"debug" description:"Outputs more info than usual"`
+		Debug             bool     `long:"debug" env:"MRLOG_DEBUG" description:"write diagnostics about mrlog itself to stderr"`
+		Format            string   `long:"format" env:"MRLOG_FORMAT" description:"output format" choice:"human+mrl" choice:"json" choice:"human" default:"human+mrl"`
+		MRLFile           string   `long:"mrl-file" env:"MRLOG_FILE" description:"also append every MRL record as a JSON line to this file"`
+		Sinks             []string `long:"sink" env:"MRLOG_SINK" env-delim:"," description:"also forward every MRL record to a collector: http(s)://, unix:// or syslog:// URL"`
+		SpoolDir          string   `long:"sink-spool" env:"MRLOG_SINK_SPOOL" description:"directory for records that could not be forwarded to a sink"`
+		OTLPEndpoint      string   `long:"otlp-endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" description:"export sections as spans to this OTLP/HTTP endpoint"`
+		RedactEnv         []string `long:"redact-env" env:"MRLOG_REDACT_ENV" env-delim:"," description:"mask the value of this environment variable in output and records (repeatable)"`
+		RedactRegex       []string `long:"redact-regex" env:"MRLOG_REDACT_REGEX" description:"mask matches of this regular expression in output and records (repeatable)"`
+		NoRedactDetectors bool     `long:"no-redact-detectors" description:"do not mask common token formats"`
+		ChainState        string   `long:"chain-state" env:"MRLOG_CHAIN_STATE" description:"link every record to the previous one in a hash chain kept in this state file"`
+		SignKey           string   `long:"sign-key" env:"MRLOG_SIGN_KEY" description:"sign every record with this Ed25519 private key, adding a sig field"`
 	}
 )
@@ -0,0 +1,172 @@
+package configfile
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jessevdk/go-flags"
+)
+
+const (
+	SourceDefault     = "default"
+	SourceCommandLine = "command line"
+	presetOption      = "preset"
+)
+
+// Value is a resolved option value and where it came from.
+type Value struct {
+	Values []string
+	Source string
+}
+
+// Config holds the loaded configuration files, lowest precedence first.
+type Config struct {
+	Files []*File
+}
+
+func (c *Config) Load(paths []string) error {
+	files, err := Load(paths)
+	if err != nil {
+		return err
+	}
+	c.Files = files
+	return nil
+}
+
+// Apply sets the options of the active command, and the global options,
+// from the configuration files. From highest to lowest precedence an option
+// comes from the command line, the environment, the selected preset, the
+// command's settings in the project file then the user file, the global
+// settings in the project file then the user file, and finally its default.
+func (c *Config) Apply(parser *flags.Parser) error {
+	if err := c.Validate(parser); err != nil {
+		return err
+	}
+
+	resolved, err := c.Resolve(parser, Active(parser))
+	if err != nil {
+		return err
+	}
+	for option, value := range resolved {
+		for i := range value.Values {
+			if err := option.Set(&value.Values[i]); err != nil {
+				return fmt.Errorf("%s: %s: %w", value.Source, option.LongName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate rejects settings that do not name an option, so that typos are
+// not silently ignored.
+func (c *Config) Validate(parser *flags.Parser) error {
+	section := parser.Find("section")
+	for _, file := range c.Files {
+		for name := range file.Global {
+			if parser.FindOptionByLongName(name) == nil {
+				return fmt.Errorf("%s: unknown option %s", file.Path, name)
+			}
+		}
+		for commandName, settings := range file.Commands {
+			command := parser.Find(commandName)
+			if command == nil {
+				return fmt.Errorf("%s: unknown command %s", file.Path, commandName)
+			}
+			for name := range settings {
+				if command.FindOptionByLongName(name) == nil {
+					return fmt.Errorf("%s: unknown option %s for command %s", file.Path, name, commandName)
+				}
+			}
+		}
+		for presetName, settings := range file.Presets {
+			for name := range settings {
+				if section == nil || section.FindOptionByLongName(name) == nil {
+					return fmt.Errorf("%s: unknown option %s in preset %s", file.Path, name, presetName)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Resolve returns the values that the configuration files give to the
+// options of command, skipping options set on the command line or in the
+// environment.
+func (c *Config) Resolve(parser *flags.Parser, command *flags.Command) (map[*flags.Option]Value, error) {
+	resolved := map[*flags.Option]Value{}
+	apply := func(lookup func(string) *flags.Option, settings Settings, source string) {
+		for _, name := range sortedKeys(settings) {
+			option := lookup(name)
+			if option != nil && !pinned(option) {
+				resolved[option] = Value{Values: settings[name], Source: source}
+			}
+		}
+	}
+
+	for _, file := range c.Files {
+		apply(parser.FindOptionByLongName, file.Global, file.Path)
+	}
+	if command == nil {
+		return resolved, nil
+	}
+	for _, file := range c.Files {
+		apply(command.FindOptionByLongName, file.Commands[command.Name], file.Path)
+	}
+
+	option := command.FindOptionByLongName(presetOption)
+	if option == nil {
+		return resolved, nil
+	}
+	name := fmt.Sprint(option.Value())
+	if value, ok := resolved[option]; ok {
+		name = value.Values[len(value.Values)-1]
+	}
+	if name == "" {
+		return resolved, nil
+	}
+	for i := len(c.Files) - 1; i >= 0; i-- {
+		if preset, ok := c.Files[i].Presets[name]; ok {
+			apply(command.FindOptionByLongName, preset, fmt.Sprintf("%s preset %s", c.Files[i].Path, name))
+			return resolved, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown preset %s", name)
+}
+
+// Active returns the innermost command being run.
+func Active(parser *flags.Parser) *flags.Command {
+	command := parser.Active
+	for command != nil && command.Active != nil {
+		command = command.Active
+	}
+	return command
+}
+
+func envSource(option *flags.Option) string {
+	key := option.EnvKeyWithNamespace()
+	if key == "" {
+		return ""
+	}
+	if _, ok := os.LookupEnv(key); !ok {
+		return ""
+	}
+	return "env " + key
+}
+
+func pinned(option *flags.Option) bool {
+	return (option.IsSet() && !option.IsSetDefault()) || envSource(option) != ""
+}
+
+// source describes where the current value of option came from.
+func source(option *flags.Option, resolved map[*flags.Option]Value) string {
+	if value, ok := resolved[option]; ok {
+		return value.Source
+	}
+	if env := envSource(option); env != "" {
+		return env
+	}
+	if option.IsSet() && !option.IsSetDefault() {
+		return SourceCommandLine
+	}
+	return SourceDefault
+}
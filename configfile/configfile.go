@@ -0,0 +1,168 @@
+package configfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ProjectFileName = ".mrlog.yml"
+	presetsKey      = "presets"
+)
+
+// Settings maps option long names to their values. Options that can be
+// repeated may have several values.
+type Settings map[string][]string
+
+// File is a parsed configuration file. Top level scalar or list keys are
+// global options, top level maps are the options of the command they are
+// named after, and presets holds named sets of section options.
+type File struct {
+	Path     string
+	Global   Settings
+	Commands map[string]Settings
+	Presets  map[string]Settings
+}
+
+// Paths returns the configuration files to load, lowest precedence first:
+// the user file in $XDG_CONFIG_HOME/mrlog, then the nearest .mrlog.yml in
+// dir or one of its parents.
+func Paths(dir string, getenv func(string) string) []string {
+	var paths []string
+
+	configHome := getenv("XDG_CONFIG_HOME")
+	if configHome == "" && getenv("HOME") != "" {
+		configHome = filepath.Join(getenv("HOME"), ".config")
+	}
+	if configHome != "" {
+		paths = append(paths, filepath.Join(configHome, "mrlog", "config.yml"))
+	}
+
+	for dir != "" {
+		project := filepath.Join(dir, ProjectFileName)
+		if _, err := os.Stat(project); err == nil {
+			paths = append(paths, project)
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return paths
+}
+
+// Load reads every configuration file that exists in paths.
+func Load(paths []string) ([]*File, error) {
+	var files []*File
+	for _, path := range paths {
+		contents, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		file, err := Parse(path, contents)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func Parse(path string, contents []byte) (*File, error) {
+	file := &File{
+		Path:     path,
+		Global:   Settings{},
+		Commands: map[string]Settings{},
+		Presets:  map[string]Settings{},
+	}
+
+	document := map[string]interface{}{}
+	if err := yaml.Unmarshal(contents, &document); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	for key, value := range document {
+		var err error
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			if key == presetsKey {
+				err = parsePresets(file.Presets, typed)
+			} else {
+				file.Commands[key], err = parseSettings(typed)
+			}
+		default:
+			file.Global[key], err = parseValues(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %s: %w", path, key, err)
+		}
+	}
+	return file, nil
+}
+
+func parsePresets(presets map[string]Settings, document map[string]interface{}) error {
+	for name, value := range document {
+		preset, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("preset %s is not a map of options", name)
+		}
+		settings, err := parseSettings(preset)
+		if err != nil {
+			return fmt.Errorf("preset %s: %w", name, err)
+		}
+		presets[name] = settings
+	}
+	return nil
+}
+
+func parseSettings(document map[string]interface{}) (Settings, error) {
+	settings := Settings{}
+	for key, value := range document {
+		values, err := parseValues(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		settings[key] = values
+	}
+	return settings, nil
+}
+
+func parseValues(value interface{}) ([]string, error) {
+	switch typed := value.(type) {
+	case []interface{}:
+		var values []string
+		for _, item := range typed {
+			if _, ok := item.([]interface{}); ok {
+				return nil, errors.New("nested lists are not supported")
+			}
+			if _, ok := item.(map[string]interface{}); ok {
+				return nil, errors.New("maps are not supported in lists")
+			}
+			values = append(values, fmt.Sprint(item))
+		}
+		return values, nil
+	case nil:
+		return nil, errors.New("missing value")
+	default:
+		return []string{fmt.Sprint(typed)}, nil
+	}
+}
+
+func sortedKeys(settings Settings) []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
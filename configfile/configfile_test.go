@@ -0,0 +1,249 @@
+package configfile_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/configfile"
+	"github.com/jessevdk/go-flags"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+type globals struct {
+	Format string   `long:"format" env:"MRLOG_TEST_FORMAT" choice:"human+mrl" choice:"json" default:"human+mrl"`
+	Sinks  []string `long:"sink"`
+	Debug  bool     `long:"debug"`
+}
+
+type sectionCommand struct {
+	Name      string        `long:"name"`
+	OnFailure string        `long:"on-failure"`
+	NoColor   bool          `long:"no-color"`
+	Timeout   time.Duration `long:"timeout"`
+	Preset    string        `long:"preset"`
+}
+
+func (s *sectionCommand) Execute(args []string) error {
+	return nil
+}
+
+var _ = Describe("Config files", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "configfile")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	write := func(path string, contents string) string {
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return path
+	}
+
+	Describe("Paths", func() {
+		It("finds the user file and the nearest project file", func() {
+			project := write(filepath.Join(dir, "project", configfile.ProjectFileName), "")
+			nested := filepath.Join(dir, "project", "a", "b")
+			Expect(os.MkdirAll(nested, 0755)).To(Succeed())
+
+			getenv := func(name string) string {
+				return map[string]string{"XDG_CONFIG_HOME": filepath.Join(dir, "xdg")}[name]
+			}
+			Expect(configfile.Paths(nested, getenv)).To(Equal([]string{
+				filepath.Join(dir, "xdg", "mrlog", "config.yml"),
+				project,
+			}))
+		})
+
+		It("falls back to ~/.config", func() {
+			getenv := func(name string) string {
+				return map[string]string{"HOME": "/home/user"}[name]
+			}
+			Expect(configfile.Paths(dir, getenv)).To(Equal([]string{"/home/user/.config/mrlog/config.yml"}))
+		})
+	})
+
+	Describe("Load", func() {
+		It("parses globals, command settings and presets, skipping missing files", func() {
+			path := write(filepath.Join(dir, "config.yml"), `
+format: json
+sink: [http://a, http://b]
+section:
+  timeout: 10m
+presets:
+  integration-tests:
+    on-failure: integration tests failed
+`)
+			files, err := configfile.Load([]string{filepath.Join(dir, "missing.yml"), path})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			Expect(files[0].Global).To(Equal(configfile.Settings{
+				"format": {"json"},
+				"sink":   {"http://a", "http://b"},
+			}))
+			Expect(files[0].Commands).To(Equal(map[string]configfile.Settings{
+				"section": {"timeout": {"10m"}},
+			}))
+			Expect(files[0].Presets).To(Equal(map[string]configfile.Settings{
+				"integration-tests": {"on-failure": {"integration tests failed"}},
+			}))
+		})
+
+		It("rejects invalid YAML", func() {
+			path := write(filepath.Join(dir, "config.yml"), "format: [json")
+			_, err := configfile.Load([]string{path})
+			Expect(err).To(MatchError(ContainSubstring("invalid config file " + path)))
+		})
+
+		It("rejects settings without a value", func() {
+			path := write(filepath.Join(dir, "config.yml"), "format:\n")
+			_, err := configfile.Load([]string{path})
+			Expect(err).To(MatchError(ContainSubstring("format: missing value")))
+		})
+	})
+
+	Describe("Apply", func() {
+		var (
+			parser  *flags.Parser
+			options *globals
+			section *sectionCommand
+			config  *configfile.Config
+			user    string
+			project string
+		)
+
+		BeforeEach(func() {
+			options = &globals{}
+			section = &sectionCommand{}
+			parser = flags.NewParser(options, flags.Default)
+			_, err := parser.AddCommand("section", "", "", section)
+			Expect(err).NotTo(HaveOccurred())
+
+			user = write(filepath.Join(dir, "user.yml"), `
+format: json
+debug: true
+section:
+  timeout: 1m
+  on-failure: user failure
+presets:
+  slow:
+    timeout: 1h
+`)
+			project = write(filepath.Join(dir, configfile.ProjectFileName), `
+sink: [http://collector]
+section:
+  timeout: 5m
+presets:
+  integration-tests:
+    timeout: 30m
+    no-color: true
+`)
+			config = &configfile.Config{}
+			Expect(config.Load([]string{user, project})).To(Succeed())
+		})
+
+		parse := func(args ...string) {
+			_, err := parser.ParseArgs(args)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		It("applies settings in precedence order", func() {
+			parse("section", "--name", "tests")
+			Expect(config.Apply(parser)).To(Succeed())
+
+			Expect(options.Format).To(Equal("json"))
+			Expect(options.Debug).To(BeTrue())
+			Expect(options.Sinks).To(Equal([]string{"http://collector"}))
+			Expect(section.Timeout).To(Equal(5 * time.Minute))
+			Expect(section.OnFailure).To(Equal("user failure"))
+			Expect(section.NoColor).To(BeFalse())
+		})
+
+		It("applies the selected preset over the command settings", func() {
+			parse("section", "--name", "tests", "--preset", "integration-tests")
+			Expect(config.Apply(parser)).To(Succeed())
+
+			Expect(section.Timeout).To(Equal(30 * time.Minute))
+			Expect(section.NoColor).To(BeTrue())
+		})
+
+		It("does not override the command line", func() {
+			parse("--format", "human+mrl", "section", "--timeout", "2s", "--preset", "slow")
+			Expect(config.Apply(parser)).To(Succeed())
+
+			Expect(options.Format).To(Equal("human+mrl"))
+			Expect(section.Timeout).To(Equal(2 * time.Second))
+		})
+
+		It("does not override the environment", func() {
+			os.Setenv("MRLOG_TEST_FORMAT", "human+mrl")
+			defer os.Unsetenv("MRLOG_TEST_FORMAT")
+
+			parse("section")
+			Expect(config.Apply(parser)).To(Succeed())
+			Expect(options.Format).To(Equal("human+mrl"))
+		})
+
+		It("rejects unknown presets", func() {
+			parse("section", "--preset", "missing")
+			Expect(config.Apply(parser)).To(MatchError("unknown preset missing"))
+		})
+
+		It("rejects unknown options", func() {
+			write(project, "colour: false\n")
+			Expect(config.Load([]string{project})).To(Succeed())
+			parse("section")
+			Expect(config.Apply(parser)).To(MatchError(project + ": unknown option colour"))
+		})
+
+		It("rejects unknown commands", func() {
+			write(project, "sectoin:\n  timeout: 1m\n")
+			Expect(config.Load([]string{project})).To(Succeed())
+			parse("section")
+			Expect(config.Apply(parser)).To(MatchError(project + ": unknown command sectoin"))
+		})
+
+		It("rejects invalid values", func() {
+			write(project, "format: xml\n")
+			Expect(config.Load([]string{project})).To(Succeed())
+			parse("section")
+			Expect(config.Apply(parser)).To(MatchError(ContainSubstring(project + ": format: Invalid value `xml'")))
+		})
+
+		Describe("show", func() {
+			It("prints the effective configuration and its sources", func() {
+				out := NewBuffer()
+				show := &configfile.ShowOpt{Parser: parser, Config: config, Out: out}
+				_, err := parser.AddCommand("show", "", "", show)
+				Expect(err).NotTo(HaveOccurred())
+
+				parse("--debug", "show")
+				Expect(config.Apply(parser)).To(Succeed())
+				Expect(show.Execute(nil)).To(Succeed())
+
+				Expect(out).To(Say("#   " + user))
+				Expect(out).To(Say("#   " + project))
+				Expect(out).To(Say(`format: "json"  # ` + user))
+				Expect(out).To(Say(`sink: \["http://collector"\]  # ` + project))
+				Expect(out).To(Say(`debug: true  # command line`))
+				Expect(out).To(Say("section:"))
+				Expect(out).To(Say(`  on-failure: "user failure"  # ` + user))
+				Expect(out).To(Say(`  timeout: 5m  # ` + project))
+				Expect(out).To(Say("presets:"))
+				Expect(out).To(Say("  integration-tests:  # " + project))
+				Expect(out).To(Say("    no-color: true"))
+				Expect(out).To(Say("    timeout: 30m"))
+				Expect(out).To(Say("  slow:  # " + user))
+			})
+		})
+	})
+})
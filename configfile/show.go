@@ -0,0 +1,126 @@
+package configfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/jessevdk/go-flags"
+)
+
+type ShowOpt struct {
+	Parser *flags.Parser `no-flag:"true"`
+	Config *Config
+	Out    io.Writer
+}
+
+// render formats option values as YAML, so that the output can be used as a
+// configuration file.
+func render(option *flags.Option, values []string) string {
+	kind := option.Field().Type.Kind()
+	if kind == reflect.Slice {
+		if values == nil {
+			values = []string{}
+		}
+		list, _ := json.Marshal(values)
+		return string(list)
+	}
+	if len(values) == 0 {
+		return `""`
+	}
+	value := values[len(values)-1]
+	if kind == reflect.String {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+func current(option *flags.Option) []string {
+	value := reflect.ValueOf(option.Value())
+	if value.Kind() != reflect.Slice {
+		return []string{fmt.Sprint(option.Value())}
+	}
+	values := []string{}
+	for i := 0; i < value.Len(); i++ {
+		values = append(values, fmt.Sprint(value.Index(i).Interface()))
+	}
+	return values
+}
+
+func (opts *ShowOpt) showOptions(indent string, options []*flags.Option, resolved map[*flags.Option]Value) {
+	for _, option := range options {
+		if option.LongName == "" || option.Field().Type.Kind() == reflect.Func {
+			continue
+		}
+		values := current(option)
+		if value, ok := resolved[option]; ok {
+			values = value.Values
+		}
+		fmt.Fprintf(opts.Out, "%s%s: %s  # %s\n", indent, option.LongName, render(option, values), source(option, resolved))
+	}
+}
+
+func (opts *ShowOpt) Execute(args []string) error {
+	fmt.Fprintln(opts.Out, "# config files, lowest precedence first:")
+	if len(opts.Config.Files) == 0 {
+		fmt.Fprintln(opts.Out, "#   none")
+	}
+	commands := map[string]bool{}
+	for _, file := range opts.Config.Files {
+		fmt.Fprintf(opts.Out, "#   %s\n", file.Path)
+		for name := range file.Commands {
+			commands[name] = true
+		}
+	}
+
+	resolved, err := opts.Config.Resolve(opts.Parser, nil)
+	if err != nil { // !branch-not-tested
+		return err
+	}
+	for _, group := range opts.Parser.Groups() {
+		opts.showOptions("", group.Options(), resolved)
+	}
+
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		command := opts.Parser.Find(name)
+		resolved, err := opts.Config.Resolve(opts.Parser, command)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(opts.Out, "%s:\n", name)
+		opts.showOptions("  ", command.Options(), resolved)
+	}
+
+	presets := map[string]*File{}
+	for _, file := range opts.Config.Files {
+		for name := range file.Presets {
+			presets[name] = file
+		}
+	}
+	if len(presets) == 0 {
+		return nil
+	}
+	names = nil
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	section := opts.Parser.Find("section")
+	fmt.Fprintln(opts.Out, "presets:")
+	for _, name := range names {
+		file := presets[name]
+		fmt.Fprintf(opts.Out, "  %s:  # %s\n", name, file.Path)
+		for _, key := range sortedKeys(file.Presets[name]) {
+			fmt.Fprintf(opts.Out, "    %s: %s\n", key, render(section.FindOptionByLongName(key), file.Presets[name][key]))
+		}
+	}
+	return nil
+}
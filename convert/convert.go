@@ -0,0 +1,149 @@
+package convert
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+type ConvertOpt struct {
+	To        string `long:"to" description:"output format" choice:"junit" required:"true"`
+	TailLines int    `long:"tail-lines" description:"number of output lines to include with failed sections" default:"20"`
+	In        io.Reader
+	Out       io.Writer
+}
+
+type property struct {
+	Name  string
+	Value string
+}
+
+type node struct {
+	Name         string
+	Start        time.Time
+	End          time.Time
+	Ended        bool
+	Result       int
+	Status       string
+	Message      string
+	Output       []string
+	Dependencies []property
+	Children     []*node
+	parent       *node
+}
+
+func (n *node) path() string {
+	if n.parent == nil || n.parent.parent == nil {
+		return n.Name
+	}
+	return n.parent.path() + "/" + n.Name
+}
+
+func (n *node) addOutput(line string, max int) {
+	for current := n; current.parent != nil; current = current.parent {
+		current.Output = append(current.Output, line)
+		if len(current.Output) > max {
+			current.Output = current.Output[len(current.Output)-max:]
+		}
+	}
+}
+
+// parse builds a tree of sections from an MRL log. The returned root node
+// holds top-level sections and any dependencies logged outside of a section.
+func parse(in io.Reader, tailLines int) (*node, error) {
+	root := &node{}
+	current := root
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		machineLog, ok, err := mrl.Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MRL record %q: %w", line, err)
+		}
+		if !ok {
+			if strings.TrimSpace(line) != "" {
+				current.addOutput(line, tailLines)
+			}
+			continue
+		}
+
+		switch {
+		case machineLog.Type == "section-start":
+			child := &node{
+				Name:   machineLog.Name,
+				Start:  machineLog.Time,
+				parent: current,
+			}
+			current.Children = append(current.Children, child)
+			current = child
+		case machineLog.Type == "section-end":
+			ending := current
+			for ending.parent != nil && ending.Name != machineLog.Name {
+				ending = ending.parent
+			}
+			if ending.parent == nil {
+				// an end without a start is still worth reporting
+				ending = &node{
+					Name:   machineLog.Name,
+					Start:  machineLog.Time,
+					parent: current,
+				}
+				current.Children = append(current.Children, ending)
+			}
+			ending.End = machineLog.Time
+			ending.Ended = true
+			ending.Result = machineLog.Result
+			ending.Status = machineLog.Status
+			ending.Message = machineLog.Message
+			current = ending.parent
+		case machineLog.Type == "section-skip":
+			current.Children = append(current.Children, &node{
+				Name:    machineLog.Name,
+				Start:   machineLog.Time,
+				End:     machineLog.Time,
+				Ended:   true,
+				Status:  mrl.StatusSkipped,
+				Message: machineLog.Message,
+				parent:  current,
+			})
+		case strings.HasSuffix(machineLog.Type, "dependency"):
+			current.Dependencies = append(current.Dependencies, property{
+				Name:  fmt.Sprintf("%s %s", machineLog.Type, machineLog.Name),
+				Value: machineLog.Version,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read log: %w", err)
+	}
+
+	return root, nil
+}
+
+func (opts *ConvertOpt) Execute(args []string) error {
+	if len(args) > 0 {
+		return errors.New("convert reads the log from stdin and takes no arguments")
+	}
+	if opts.TailLines < 0 {
+		return errors.New("--tail-lines must not be negative")
+	}
+
+	root, err := parse(opts.In, opts.TailLines)
+	if err != nil {
+		return err
+	}
+
+	switch opts.To {
+	case "junit":
+		return writeJUnit(opts.Out, root)
+	default:
+		return fmt.Errorf("unsupported output format: %s", opts.To)
+	}
+}
@@ -0,0 +1,201 @@
+package convert_test
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/cf-platform-eng/mrlog/convert"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+type testCase struct {
+	Name      string `xml:"name,attr"`
+	Classname string `xml:"classname,attr"`
+	Time      string `xml:"time,attr"`
+	Failure   *struct {
+		Message  string `xml:"message,attr"`
+		Contents string `xml:",chardata"`
+	} `xml:"failure"`
+	Error *struct {
+		Message string `xml:"message,attr"`
+	} `xml:"error"`
+	Skipped *struct {
+		Message string `xml:"message,attr"`
+	} `xml:"skipped"`
+}
+
+type testSuite struct {
+	Name       string `xml:"name,attr"`
+	Tests      int    `xml:"tests,attr"`
+	Failures   int    `xml:"failures,attr"`
+	Skipped    int    `xml:"skipped,attr"`
+	Time       string `xml:"time,attr"`
+	Properties []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"properties>property"`
+	Cases []testCase `xml:"testcase"`
+}
+
+type testSuites struct {
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Suites   []testSuite `xml:"testsuite"`
+}
+
+const log = `section-start: 'build' MRL:{"type":"section-start","name":"build","time":"2021-02-22T13:21:40Z"}
+binary dependency: 'kubectl' version 'v1.20.2' MRL:{"type":"binary dependency","version":"v1.20.2","name":"kubectl","time":"2021-02-22T13:21:40Z"}
+section-start: 'compile' MRL:{"type":"section-start","name":"compile","time":"2021-02-22T13:21:41Z"}
+compiling
+section-end: 'compile' result: 0 MRL:{"type":"section-end","name":"compile","time":"2021-02-22T13:21:43.5Z"}
+
+section-start: 'test' MRL:{"type":"section-start","name":"test","time":"2021-02-22T13:21:44Z"}
+line one
+line two
+line three
+Section subcommand failed with 2: exit status 2
+section-end: 'test' result: 2 message: 'tests failed' MRL:{"type":"section-end","name":"test","result":2,"time":"2021-02-22T13:21:50Z","message":"tests failed"}
+
+section-skip: 'deploy' reason: 'an earlier step failed' MRL:{"type":"section-skip","name":"deploy","status":"skipped","time":"2021-02-22T13:21:50Z","message":"an earlier step failed"}
+
+section-start: 'lint' MRL:{"type":"section-start","name":"lint","time":"2021-02-22T13:21:50Z"}
+section-end: 'lint' result: 3 status: warning MRL:{"type":"section-end","name":"lint","result":3,"status":"warning","time":"2021-02-22T13:21:51Z"}
+
+section-end: 'build' result: 2 MRL:{"type":"section-end","name":"build","result":2,"time":"2021-02-22T13:21:51Z"}
+
+section-start: 'publish' MRL:{"type":"section-start","name":"publish","time":"2021-02-22T13:22:00Z"}
+`
+
+var _ = Describe("Convert", func() {
+	var (
+		out     *Buffer
+		context *convert.ConvertOpt
+	)
+
+	BeforeEach(func() {
+		out = NewBuffer()
+		context = &convert.ConvertOpt{
+			To:        "junit",
+			TailLines: 3,
+			In:        strings.NewReader(log),
+			Out:       out,
+		}
+	})
+
+	It("rejects arguments", func() {
+		err := context.Execute([]string{"build.log"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("takes no arguments"))
+	})
+
+	It("rejects a negative number of output lines", func() {
+		context.TailLines = -1
+		Expect(context.Execute([]string{})).To(MatchError("--tail-lines must not be negative"))
+	})
+
+	It("rejects invalid records", func() {
+		context.In = strings.NewReader("section-start: 'x' MRL:{not json\n")
+		err := context.Execute([]string{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid MRL record"))
+	})
+
+	It("decides the outcome of a section by its status", func() {
+		context.In = strings.NewReader(`section-end: 'failed' result: 0 MRL:{"type":"section-end","name":"failed","status":"failed","time":"2021-02-22T13:21:40Z"}
+section-end: 'timeout' result: 0 MRL:{"type":"section-end","name":"timeout","status":"timeout","time":"2021-02-22T13:21:40Z"}
+section-end: 'cancelled' result: 0 MRL:{"type":"section-end","name":"cancelled","status":"cancelled","time":"2021-02-22T13:21:40Z"}
+section-end: 'passed' result: 0 MRL:{"type":"section-end","name":"passed","status":"passed","time":"2021-02-22T13:21:40Z"}
+`)
+		Expect(context.Execute([]string{})).To(Succeed())
+		var report testSuites
+		Expect(xml.Unmarshal(out.Contents(), &report)).To(Succeed())
+		Expect(report.Failures).To(Equal(2))
+		Expect(report.Skipped).To(Equal(1))
+
+		cases := report.Suites[0].Cases
+		Expect(cases[0].Failure.Message).To(Equal("section failed with result 0"))
+		Expect(cases[1].Failure.Message).To(Equal("section timed out"))
+		Expect(cases[2].Skipped.Message).To(Equal("section was cancelled"))
+		Expect(cases[3].Failure).To(BeNil())
+		Expect(cases[3].Skipped).To(BeNil())
+	})
+
+	Context("junit", func() {
+		var report testSuites
+
+		BeforeEach(func() {
+			Expect(context.Execute([]string{})).To(Succeed())
+			Expect(string(out.Contents())).To(HavePrefix(xml.Header))
+			Expect(xml.Unmarshal(out.Contents(), &report)).To(Succeed())
+		})
+
+		It("maps top level sections to test cases", func() {
+			Expect(report.Tests).To(Equal(6))
+			Expect(report.Failures).To(Equal(2))
+			Expect(report.Skipped).To(Equal(1))
+			Expect(report.Suites).To(HaveLen(2))
+
+			root := report.Suites[0]
+			Expect(root.Name).To(Equal("mrlog"))
+			Expect(root.Cases).To(HaveLen(2))
+			Expect(root.Cases[0].Name).To(Equal("build"))
+			Expect(root.Cases[0].Time).To(Equal("11.000"))
+			Expect(root.Cases[0].Failure).NotTo(BeNil())
+			Expect(root.Cases[0].Failure.Message).To(Equal("section failed with result 2"))
+		})
+
+		It("reports sections that never ended as errors", func() {
+			publish := report.Suites[0].Cases[1]
+			Expect(publish.Name).To(Equal("publish"))
+			Expect(publish.Error).NotTo(BeNil())
+			Expect(publish.Error.Message).To(Equal("section did not end"))
+		})
+
+		It("maps nested sections to test suites", func() {
+			build := report.Suites[1]
+			Expect(build.Name).To(Equal("build"))
+			Expect(build.Tests).To(Equal(4))
+			Expect(build.Failures).To(Equal(1))
+			Expect(build.Time).To(Equal("11.000"))
+
+			Expect(build.Cases[0].Name).To(Equal("compile"))
+			Expect(build.Cases[0].Classname).To(Equal("build"))
+			Expect(build.Cases[0].Time).To(Equal("2.500"))
+			Expect(build.Cases[0].Failure).To(BeNil())
+		})
+
+		It("includes the failure message and the output tail", func() {
+			test := report.Suites[1].Cases[1]
+			Expect(test.Name).To(Equal("test"))
+			Expect(test.Failure).NotTo(BeNil())
+			Expect(test.Failure.Message).To(Equal("tests failed"))
+			Expect(test.Failure.Contents).To(Equal("line two\nline three\nSection subcommand failed with 2: exit status 2"))
+		})
+
+		It("reports skipped sections as skipped and allowed failures as passed", func() {
+			build := report.Suites[1]
+			Expect(build.Skipped).To(Equal(1))
+
+			deploy := build.Cases[2]
+			Expect(deploy.Name).To(Equal("deploy"))
+			Expect(deploy.Skipped).NotTo(BeNil())
+			Expect(deploy.Skipped.Message).To(Equal("an earlier step failed"))
+			Expect(deploy.Failure).To(BeNil())
+
+			lint := build.Cases[3]
+			Expect(lint.Name).To(Equal("lint"))
+			Expect(lint.Failure).To(BeNil())
+			Expect(lint.Skipped).To(BeNil())
+		})
+
+		It("maps dependencies to properties", func() {
+			build := report.Suites[1]
+			Expect(build.Properties).To(HaveLen(1))
+			Expect(build.Properties[0].Name).To(Equal("binary dependency kubectl"))
+			Expect(build.Properties[0].Value).To(Equal("v1.20.2"))
+		})
+	})
+})
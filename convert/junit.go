@@ -0,0 +1,219 @@
+package convert
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       string           `xml:"time,attr"`
+	Timestamp  string           `xml:"timestamp,attr,omitempty"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Cases      []junitTestCase  `xml:"testcase"`
+}
+
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+}
+
+const rootSuiteName = "mrlog"
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func (n *node) duration() time.Duration {
+	if !n.Ended {
+		return 0
+	}
+	return n.End.Sub(n.Start)
+}
+
+func (n *node) testCase(classname string) junitTestCase {
+	testCase := junitTestCase{
+		Name:      n.Name,
+		Classname: classname,
+		Time:      seconds(n.duration()),
+	}
+
+	output := strings.Join(n.Output, "\n")
+	if !n.Ended {
+		testCase.Error = &junitFailure{
+			Message:  "section did not end",
+			Type:     "incomplete",
+			Contents: output,
+		}
+		return testCase
+	}
+
+	message := n.Message
+	switch n.status() {
+	case mrl.StatusSkipped:
+		testCase.Skipped = &junitSkipped{Message: message}
+	case mrl.StatusCancelled:
+		if message == "" {
+			message = "section was cancelled"
+		}
+		testCase.Skipped = &junitSkipped{Message: message}
+	case mrl.StatusFailed, mrl.StatusTimeout:
+		if message == "" {
+			message = fmt.Sprintf("section failed with result %d", n.Result)
+			if n.Status == mrl.StatusTimeout {
+				message = "section timed out"
+			}
+		}
+		testCase.Failure = &junitFailure{
+			Message:  message,
+			Type:     fmt.Sprintf("result %d", n.Result),
+			Contents: output,
+		}
+	}
+	return testCase
+}
+
+// status returns how the section ended: its recorded status or, for records
+// written before sections had one, passed or failed by its result.
+func (n *node) status() string {
+	if n.Status != "" {
+		return n.Status
+	}
+	if n.Result != 0 {
+		return mrl.StatusFailed
+	}
+	return mrl.StatusPassed
+}
+
+// suites flattens the section tree into test suites. Every section becomes a
+// test case in the suite of its parent, and every section with nested
+// sections becomes a test suite of its own.
+func (n *node) suites() []junitTestSuite {
+	name := rootSuiteName
+	if n.parent != nil {
+		name = n.path()
+	}
+
+	suite := junitTestSuite{
+		Name: name,
+		Time: seconds(n.duration()),
+	}
+	if n.parent != nil {
+		suite.Timestamp = n.Start.Format(time.RFC3339)
+	}
+
+	properties := append([]property{}, n.Dependencies...)
+	var nested []junitTestSuite
+	for _, child := range n.Children {
+		testCase := child.testCase(name)
+		suite.Tests++
+		if testCase.Failure != nil {
+			suite.Failures++
+		}
+		if testCase.Error != nil {
+			suite.Errors++
+		}
+		if testCase.Skipped != nil {
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+
+		if len(child.Children) > 0 {
+			nested = append(nested, child.suites()...)
+		} else {
+			properties = append(properties, child.Dependencies...)
+		}
+	}
+
+	if len(properties) > 0 {
+		suite.Properties = &junitProperties{}
+		for _, p := range properties {
+			suite.Properties.Properties = append(suite.Properties.Properties, junitProperty{
+				Name:  p.Name,
+				Value: p.Value,
+			})
+		}
+	}
+
+	if n.parent == nil {
+		var total time.Duration
+		for _, child := range n.Children {
+			total += child.duration()
+		}
+		suite.Time = seconds(total)
+	}
+
+	return append([]junitTestSuite{suite}, nested...)
+}
+
+func writeJUnit(out io.Writer, root *node) error {
+	report := junitTestSuites{
+		Name:   rootSuiteName,
+		Suites: root.suites(),
+	}
+	for _, suite := range report.Suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+	}
+	report.Time = report.Suites[0].Time
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+
+	if _, err := io.WriteString(out, "\n"); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+	return nil
+}
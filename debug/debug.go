@@ -0,0 +1,44 @@
+package debug
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Logger writes diagnostics about mrlog itself, prefixed with the time since
+// Start, to Out. It is enabled by --debug and writes to stderr so that it
+// never mixes with the records on stdout. A nil Logger, or one without Out,
+// is disabled.
+type Logger struct {
+	Out   io.Writer
+	Start time.Time
+}
+
+func (l *Logger) Enabled() bool {
+	return l != nil && l.Out != nil
+}
+
+func (l *Logger) Printf(format string, args ...interface{}) {
+	if !l.Enabled() {
+		return
+	}
+	message := strings.TrimRight(fmt.Sprintf(format, args...), "\n")
+	for _, line := range strings.Split(message, "\n") {
+		fmt.Fprintf(l.Out, "mrlog debug [%8.3fs] %s\n", time.Since(l.Start).Seconds(), line)
+	}
+}
+
+// Time logs how long a phase took once the returned function is called:
+//
+//	defer logger.Time("configure")()
+func (l *Logger) Time(phase string) func() {
+	if !l.Enabled() {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		l.Printf("%s took %s", phase, time.Since(start).Round(time.Microsecond))
+	}
+}
@@ -0,0 +1,43 @@
+package debug_test
+
+import (
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/debug"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Logger", func() {
+	var (
+		out    *Buffer
+		logger *debug.Logger
+	)
+
+	BeforeEach(func() {
+		out = NewBuffer()
+		logger = &debug.Logger{Out: out, Start: time.Now()}
+	})
+
+	It("prefixes every line with the elapsed time", func() {
+		logger.Printf("config file %s\nsecond line\n", "a.yml")
+		Expect(out).To(Say(`mrlog debug \[ *0\.\d{3}s\] config file a.yml\n`))
+		Expect(out).To(Say(`mrlog debug \[ *0\.\d{3}s\] second line\n`))
+	})
+
+	It("times phases", func() {
+		done := logger.Time("exec")
+		done()
+		Expect(out).To(Say(`\] exec took \d`))
+	})
+
+	It("is disabled without an output", func() {
+		var disabled *debug.Logger
+		Expect(disabled.Enabled()).To(BeFalse())
+		disabled.Printf("nothing")
+		disabled.Time("nothing")()
+
+		Expect((&debug.Logger{}).Enabled()).To(BeFalse())
+	})
+})
@@ -3,10 +3,10 @@ package dependency
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 
 	"github.com/cf-platform-eng/mrlog/clock"
 	"github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/trace"
 )
 
 type Identities struct {
@@ -18,8 +18,9 @@ type Identities struct {
 
 type DependencyOpt struct {
 	Identities
-	Out   io.Writer
-	Clock clock.Clock
+	Printer *mrl.Printer
+	Clock   clock.Clock
+	Tracer  *trace.Tracer
 }
 
 func (opts *DependencyOpt) Execute(args []string) error {
@@ -33,11 +34,6 @@ func (opts *DependencyOpt) Execute(args []string) error {
 		opts.Name,
 		opts.Version)
 
-	_, err := fmt.Fprint(opts.Out, humanReadable)
-	if err != nil { // !branch-not-tested
-		return err
-	}
-
 	machineLog := &mrl.MachineReadableLog{
 		Type:     dependency,
 		Version:  opts.Version,
@@ -47,21 +43,32 @@ func (opts *DependencyOpt) Execute(args []string) error {
 	}
 
 	if opts.Metadata != "" {
-		err = json.Unmarshal([]byte(opts.Metadata), &machineLog.Metadata)
+		err := json.Unmarshal([]byte(opts.Metadata), &machineLog.Metadata)
 		if err != nil {
 			return fmt.Errorf("invalid metadata: %w", err)
 		}
 	}
 
-	machineLogJSON, err := json.Marshal(machineLog)
-	if err != nil {
+	if err := opts.Printer.Print(humanReadable, machineLog, "\n"); err != nil {
 		return err
 	}
 
-	_, err = fmt.Fprintf(opts.Out, " MRL:%s\n", string(machineLogJSON))
-	if err != nil { // !branch-not-tested
-		return err
+	// Dependencies logged from within a traced section are recorded as an
+	// event on a span of their own, as the section span belongs to the
+	// parent mrlog process. The span takes the name and version of the
+	// record, which the Printer has redacted.
+	if opts.Tracer.Enabled() && opts.Tracer.Parent != "" {
+		span := opts.Tracer.Start(fmt.Sprintf("%s %s", dependency, machineLog.Name), machineLog.Time)
+		span.Events = append(span.Events, trace.Event{
+			Name: dependency,
+			Time: machineLog.Time,
+			Attributes: map[string]interface{}{
+				"mrlog.dependency.name":    machineLog.Name,
+				"mrlog.dependency.version": machineLog.Version,
+				"mrlog.dependency.type":    opts.DependencyType,
+			},
+		})
+		opts.Tracer.End(span, machineLog.Time)
 	}
-
 	return nil
 }
@@ -0,0 +1,142 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/cf-platform-eng/mrlog/clock"
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+// DefaultAllowed are the environment variables recorded unless --no-defaults
+// is given. Every other variable is omitted, as it may hold a secret.
+var DefaultAllowed = []string{
+	"CI",
+	"LANG",
+	"LC_ALL",
+	"PATH",
+	"SHELL",
+	"TERM",
+	"TZ",
+}
+
+type Snapshot struct {
+	OS          string            `json:"os"`
+	Kernel      string            `json:"kernel,omitempty"`
+	Arch        string            `json:"arch"`
+	CPUs        int               `json:"cpus"`
+	MemoryBytes uint64            `json:"memory_bytes,omitempty"`
+	Hostname    string            `json:"hostname,omitempty"`
+	Container   bool              `json:"container"`
+	GoVersion   string            `json:"go_version"`
+	Env         map[string]string `json:"env,omitempty"`
+}
+
+//go:generate counterfeiter System
+type System interface {
+	Snapshot() (*Snapshot, error)
+}
+
+type EnvOpt struct {
+	Allow      []string `long:"allow" description:"environment variable to record, may end in * to match a prefix (repeatable)"`
+	NoDefaults bool     `long:"no-defaults" description:"do not record the default set of environment variables"`
+	Printer    *mrl.Printer
+	Clock      clock.Clock
+	System     System
+	Environ    func() []string
+}
+
+func (opts *EnvOpt) allowed(name string) bool {
+	patterns := opts.Allow
+	if !opts.NoDefaults {
+		patterns = append(append([]string{}, DefaultAllowed...), patterns...)
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (opts *EnvOpt) env() map[string]string {
+	environ := opts.Environ
+	if environ == nil {
+		environ = os.Environ
+	}
+
+	env := map[string]string{}
+	for _, variable := range environ() {
+		name, value, _ := strings.Cut(variable, "=")
+		if opts.allowed(name) {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+func formatBytes(bytes uint64) string {
+	const gib = 1024 * 1024 * 1024
+	return fmt.Sprintf("%.1f GiB", float64(bytes)/gib)
+}
+
+func (opts *EnvOpt) Execute(args []string) error {
+	snapshot, err := opts.System.Snapshot()
+	if err != nil {
+		return fmt.Errorf("failed to inspect environment: %w", err)
+	}
+	snapshot.Env = opts.env()
+
+	humanReadable := fmt.Sprintf("environment: %s/%s", snapshot.OS, snapshot.Arch)
+	if snapshot.Kernel != "" {
+		humanReadable += fmt.Sprintf(" kernel '%s'", snapshot.Kernel)
+	}
+	humanReadable += fmt.Sprintf(" cpus: %d", snapshot.CPUs)
+	if snapshot.MemoryBytes != 0 {
+		humanReadable += fmt.Sprintf(" memory: %s", formatBytes(snapshot.MemoryBytes))
+	}
+	if snapshot.Container {
+		humanReadable += " in a container"
+	}
+
+	names := make([]string, 0, len(snapshot.Env))
+	for name := range snapshot.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		humanReadable += fmt.Sprintf(" env: %s", strings.Join(names, ","))
+	}
+
+	machineLog := &mrl.MachineReadableLog{
+		Type:     "environment",
+		Name:     snapshot.Hostname,
+		Metadata: snapshot,
+		Time:     opts.Clock.Now(),
+	}
+	return opts.Printer.Print(humanReadable, machineLog, "\n")
+}
+
+// Host inspects the machine mrlog is running on.
+type Host struct{}
+
+func (h *Host) Snapshot() (*Snapshot, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		Hostname:  hostname,
+		GoVersion: runtime.Version(),
+	}
+	inspectHost(snapshot)
+	return snapshot, nil
+}
@@ -0,0 +1,116 @@
+package environment_test
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"runtime"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/clock/clockfakes"
+	"github.com/cf-platform-eng/mrlog/environment"
+	"github.com/cf-platform-eng/mrlog/environment/environmentfakes"
+	"github.com/cf-platform-eng/mrlog/mrl"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Environment", func() {
+	var (
+		out     *Buffer
+		system  *environmentfakes.FakeSystem
+		context *environment.EnvOpt
+	)
+
+	BeforeEach(func() {
+		out = NewBuffer()
+
+		clock := &clockfakes.FakeClock{}
+		clock.NowReturns(time.Date(1973, 11, 29, 10, 15, 01, 00, time.UTC))
+
+		system = &environmentfakes.FakeSystem{}
+		system.SnapshotReturns(&environment.Snapshot{
+			OS:          "linux",
+			Kernel:      "5.15.0",
+			Arch:        "amd64",
+			CPUs:        8,
+			MemoryBytes: 16 * 1024 * 1024 * 1024,
+			Hostname:    "worker-1",
+			Container:   true,
+			GoVersion:   "go1.23.0",
+		}, nil)
+
+		context = &environment.EnvOpt{
+			Printer: &mrl.Printer{Out: out},
+			Clock:   clock,
+			System:  system,
+			Environ: func() []string {
+				return []string{
+					"PATH=/usr/bin",
+					"GITHUB_TOKEN=secret",
+					"BUILD_NAME=42",
+					"BUILD_TEAM_NAME=main",
+				}
+			},
+		}
+	})
+
+	record := func() map[string]interface{} {
+		matches := regexp.MustCompile(`\sMRL:(.*)\n`).FindSubmatch(out.Contents())
+		Expect(matches).To(HaveLen(2))
+		machineReadable := map[string]interface{}{}
+		Expect(json.Unmarshal(matches[1], &machineReadable)).To(Succeed())
+		return machineReadable
+	}
+
+	It("logs the environment", func() {
+		Expect(context.Execute([]string{})).To(Succeed())
+		Expect(out).To(Say("environment: linux/amd64 kernel '5.15.0' cpus: 8 memory: 16.0 GiB in a container env: PATH"))
+
+		machineReadable := record()
+		Expect(machineReadable["type"]).To(Equal("environment"))
+		Expect(machineReadable["name"]).To(Equal("worker-1"))
+
+		metadata := machineReadable["metadata"].(map[string]interface{})
+		Expect(metadata["os"]).To(Equal("linux"))
+		Expect(metadata["kernel"]).To(Equal("5.15.0"))
+		Expect(metadata["cpus"]).To(Equal(float64(8)))
+		Expect(metadata["memory_bytes"]).To(Equal(float64(16 * 1024 * 1024 * 1024)))
+		Expect(metadata["container"]).To(BeTrue())
+		Expect(metadata["go_version"]).To(Equal("go1.23.0"))
+	})
+
+	It("omits environment variables that are not allowed", func() {
+		Expect(context.Execute([]string{})).To(Succeed())
+		env := record()["metadata"].(map[string]interface{})["env"]
+		Expect(env).To(Equal(map[string]interface{}{"PATH": "/usr/bin"}))
+	})
+
+	It("records allowed environment variables", func() {
+		context.Allow = []string{"BUILD_*"}
+		context.NoDefaults = true
+		Expect(context.Execute([]string{})).To(Succeed())
+		env := record()["metadata"].(map[string]interface{})["env"]
+		Expect(env).To(Equal(map[string]interface{}{"BUILD_NAME": "42", "BUILD_TEAM_NAME": "main"}))
+	})
+
+	It("returns an error when the environment cannot be inspected", func() {
+		system.SnapshotReturns(nil, errors.New("no hostname"))
+		err := context.Execute([]string{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("failed to inspect environment: no hostname"))
+	})
+
+	Describe("Host", func() {
+		It("inspects the current machine", func() {
+			snapshot, err := (&environment.Host{}).Snapshot()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshot.OS).To(Equal(runtime.GOOS))
+			Expect(snapshot.Arch).To(Equal(runtime.GOARCH))
+			Expect(snapshot.CPUs).To(Equal(runtime.NumCPU()))
+			Expect(snapshot.GoVersion).To(Equal(runtime.Version()))
+			Expect(snapshot.Hostname).NotTo(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,14 @@
+package environment
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func inspectHost(snapshot *Snapshot) {
+	if release, err := unix.Sysctl("kern.osrelease"); err == nil {
+		snapshot.Kernel = release
+	}
+	if memory, err := unix.SysctlUint64("hw.memsize"); err == nil {
+		snapshot.MemoryBytes = memory
+	}
+}
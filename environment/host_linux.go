@@ -0,0 +1,58 @@
+package environment
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var containerCgroups = []string{"docker", "kubepods", "containerd", "lxc", "libpod"}
+
+func inspectHost(snapshot *Snapshot) {
+	if release, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		snapshot.Kernel = strings.TrimSpace(string(release))
+	}
+	snapshot.MemoryBytes = memTotal()
+	snapshot.Container = inContainer()
+}
+
+func memTotal() uint64 {
+	meminfo, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer meminfo.Close()
+
+	scanner := bufio.NewScanner(meminfo)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kilobytes, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kilobytes * 1024
+		}
+	}
+	return 0
+}
+
+func inContainer() bool {
+	for _, marker := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(marker); err == nil {
+			return true
+		}
+	}
+
+	cgroup, err := os.ReadFile("/proc/1/cgroup")
+	if err != nil {
+		return false
+	}
+	for _, name := range containerCgroups {
+		if strings.Contains(string(cgroup), name) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,29 @@
 package mrlog
 
 import (
+	"errors"
 	"io"
+	"os"
 	os_exec "os/exec"
+	"sync"
 
 	"github.com/cf-platform-eng/mrlog/exec"
+	"github.com/cf-platform-eng/mrlog/mrl"
 )
 
 type Cmd struct {
-	Cmd *os_exec.Cmd
+	Cmd   *os_exec.Cmd
+	mutex sync.Mutex
+	pty   bool
+	// processGroup is whether mrlog has to signal the processes the process
+	// starts, and so has to start it in a process group of its own
+	processGroup bool
+	// group is whether the process leads a process group of its own, which
+	// signals are sent to
+	group bool
+	// tty is mrlog's terminal while the process group is its foreground
+	// process group
+	tty *os.File
 }
 
 func (cmd *Cmd) SetOutput(writer io.Writer) {
@@ -16,8 +31,85 @@ func (cmd *Cmd) SetOutput(writer io.Writer) {
 	cmd.Cmd.Stderr = writer
 }
 
+func (cmd *Cmd) SetEnv(env []string) {
+	cmd.Cmd.Env = env
+}
+
+func (cmd *Cmd) SetDir(dir string) {
+	cmd.Cmd.Dir = dir
+}
+
+func (cmd *Cmd) SetStdin(reader io.Reader) {
+	cmd.Cmd.Stdin = reader
+}
+
+func (cmd *Cmd) SetPty(pty bool) {
+	cmd.pty = pty
+}
+
+func (cmd *Cmd) SetProcessGroup(group bool) {
+	cmd.processGroup = group
+}
+
 func (cmd *Cmd) Run() error {
-	return cmd.Cmd.Run()
+	if cmd.pty {
+		return cmd.runPty()
+	}
+
+	// a process group of its own is only needed for mrlog to signal it, or
+	// when the output is not a terminal, as in CI, where it also keeps
+	// forwarded signals from missing the processes it starts
+	if cmd.processGroup || !isTerminal(os.Stdout) || !isTerminal(os.Stderr) {
+		cmd.setProcessGroup()
+	}
+
+	// the process is started under the lock so that it can be signalled
+	// from another goroutine while Run waits for it
+	cmd.mutex.Lock()
+	err := cmd.Cmd.Start()
+	cmd.mutex.Unlock()
+	if err != nil {
+		cmd.releaseTerminal()
+		return err
+	}
+	defer cmd.releaseTerminal()
+	defer cmd.forwardSignals()()
+	return cmd.Cmd.Wait()
+}
+
+func (cmd *Cmd) Signal(sig os.Signal) error {
+	cmd.mutex.Lock()
+	defer cmd.mutex.Unlock()
+	if cmd.Cmd.Process == nil {
+		return errors.New("process not started")
+	}
+	if cmd.group {
+		// reaching the processes it started, which may hold its output open
+		return signalGroup(cmd.Cmd.Process.Pid, sig)
+	}
+	return cmd.Cmd.Process.Signal(sig)
+}
+
+func (cmd *Cmd) Pid() int {
+	cmd.mutex.Lock()
+	defer cmd.mutex.Unlock()
+	if cmd.Cmd.Process == nil {
+		return 0
+	}
+	return cmd.Cmd.Process.Pid
+}
+
+func (cmd *Cmd) Usage() *mrl.Usage {
+	state := cmd.Cmd.ProcessState
+	if state == nil {
+		return nil
+	}
+	usage := &mrl.Usage{
+		UserCPU:   state.UserTime().Seconds(),
+		SystemCPU: state.SystemTime().Seconds(),
+	}
+	addRusage(usage, state.SysUsage())
+	return usage
 }
 
 type Exec struct {
@@ -2,6 +2,9 @@ package exec
 
 import (
 	"io"
+	"os"
+
+	"github.com/cf-platform-eng/mrlog/mrl"
 )
 
 //go:generate counterfeiter Exec
@@ -12,5 +15,22 @@ type Exec interface {
 //go:generate counterfeiter Cmd
 type Cmd interface {
 	SetOutput(writer io.Writer)
+	SetEnv(env []string)
+	SetDir(dir string)
+	SetStdin(reader io.Reader)
 	Run() error
+	Signal(sig os.Signal) error
+	// Pid returns the process ID once the process has started, or 0.
+	Pid() int
+	// SetCgroup places the process in the cgroup v2 group open as fd when
+	// it starts. It has no effect outside Linux.
+	SetCgroup(fd int)
+	// SetPty runs the process with a pseudo-terminal for its output, so
+	// that it behaves as it would in a terminal.
+	SetPty(pty bool)
+	// SetProcessGroup starts the process in a process group of its own, so
+	// that Signal also reaches the processes it starts.
+	SetProcessGroup(group bool)
+	// Usage returns the resources used by the process once it has exited.
+	Usage() *mrl.Usage
 }
@@ -3,12 +3,24 @@ package execfakes
 
 import (
 	"io"
+	"os"
 	"sync"
 
 	"github.com/cf-platform-eng/mrlog/exec"
+	"github.com/cf-platform-eng/mrlog/mrl"
 )
 
 type FakeCmd struct {
+	PidStub        func() int
+	pidMutex       sync.RWMutex
+	pidArgsForCall []struct {
+	}
+	pidReturns struct {
+		result1 int
+	}
+	pidReturnsOnCall map[int]struct {
+		result1 int
+	}
 	RunStub        func() error
 	runMutex       sync.RWMutex
 	runArgsForCall []struct {
@@ -19,29 +31,134 @@ type FakeCmd struct {
 	runReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SetCgroupStub        func(int)
+	setCgroupMutex       sync.RWMutex
+	setCgroupArgsForCall []struct {
+		arg1 int
+	}
+	SetDirStub        func(string)
+	setDirMutex       sync.RWMutex
+	setDirArgsForCall []struct {
+		arg1 string
+	}
+	SetEnvStub        func([]string)
+	setEnvMutex       sync.RWMutex
+	setEnvArgsForCall []struct {
+		arg1 []string
+	}
 	SetOutputStub        func(io.Writer)
 	setOutputMutex       sync.RWMutex
 	setOutputArgsForCall []struct {
 		arg1 io.Writer
 	}
+	SetProcessGroupStub        func(bool)
+	setProcessGroupMutex       sync.RWMutex
+	setProcessGroupArgsForCall []struct {
+		arg1 bool
+	}
+	SetPtyStub        func(bool)
+	setPtyMutex       sync.RWMutex
+	setPtyArgsForCall []struct {
+		arg1 bool
+	}
+	SetStdinStub        func(io.Reader)
+	setStdinMutex       sync.RWMutex
+	setStdinArgsForCall []struct {
+		arg1 io.Reader
+	}
+	SignalStub        func(os.Signal) error
+	signalMutex       sync.RWMutex
+	signalArgsForCall []struct {
+		arg1 os.Signal
+	}
+	signalReturns struct {
+		result1 error
+	}
+	signalReturnsOnCall map[int]struct {
+		result1 error
+	}
+	UsageStub        func() *mrl.Usage
+	usageMutex       sync.RWMutex
+	usageArgsForCall []struct {
+	}
+	usageReturns struct {
+		result1 *mrl.Usage
+	}
+	usageReturnsOnCall map[int]struct {
+		result1 *mrl.Usage
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeCmd) Pid() int {
+	fake.pidMutex.Lock()
+	ret, specificReturn := fake.pidReturnsOnCall[len(fake.pidArgsForCall)]
+	fake.pidArgsForCall = append(fake.pidArgsForCall, struct {
+	}{})
+	stub := fake.PidStub
+	fakeReturns := fake.pidReturns
+	fake.recordInvocation("Pid", []interface{}{})
+	fake.pidMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCmd) PidCallCount() int {
+	fake.pidMutex.RLock()
+	defer fake.pidMutex.RUnlock()
+	return len(fake.pidArgsForCall)
+}
+
+func (fake *FakeCmd) PidCalls(stub func() int) {
+	fake.pidMutex.Lock()
+	defer fake.pidMutex.Unlock()
+	fake.PidStub = stub
+}
+
+func (fake *FakeCmd) PidReturns(result1 int) {
+	fake.pidMutex.Lock()
+	defer fake.pidMutex.Unlock()
+	fake.PidStub = nil
+	fake.pidReturns = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *FakeCmd) PidReturnsOnCall(i int, result1 int) {
+	fake.pidMutex.Lock()
+	defer fake.pidMutex.Unlock()
+	fake.PidStub = nil
+	if fake.pidReturnsOnCall == nil {
+		fake.pidReturnsOnCall = make(map[int]struct {
+			result1 int
+		})
+	}
+	fake.pidReturnsOnCall[i] = struct {
+		result1 int
+	}{result1}
+}
+
 func (fake *FakeCmd) Run() error {
 	fake.runMutex.Lock()
 	ret, specificReturn := fake.runReturnsOnCall[len(fake.runArgsForCall)]
 	fake.runArgsForCall = append(fake.runArgsForCall, struct {
 	}{})
+	stub := fake.RunStub
+	fakeReturns := fake.runReturns
 	fake.recordInvocation("Run", []interface{}{})
 	fake.runMutex.Unlock()
-	if fake.RunStub != nil {
-		return fake.RunStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.runReturns
 	return fakeReturns.result1
 }
 
@@ -80,14 +197,116 @@ func (fake *FakeCmd) RunReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeCmd) SetCgroup(arg1 int) {
+	fake.setCgroupMutex.Lock()
+	fake.setCgroupArgsForCall = append(fake.setCgroupArgsForCall, struct {
+		arg1 int
+	}{arg1})
+	stub := fake.SetCgroupStub
+	fake.recordInvocation("SetCgroup", []interface{}{arg1})
+	fake.setCgroupMutex.Unlock()
+	if stub != nil {
+		fake.SetCgroupStub(arg1)
+	}
+}
+
+func (fake *FakeCmd) SetCgroupCallCount() int {
+	fake.setCgroupMutex.RLock()
+	defer fake.setCgroupMutex.RUnlock()
+	return len(fake.setCgroupArgsForCall)
+}
+
+func (fake *FakeCmd) SetCgroupCalls(stub func(int)) {
+	fake.setCgroupMutex.Lock()
+	defer fake.setCgroupMutex.Unlock()
+	fake.SetCgroupStub = stub
+}
+
+func (fake *FakeCmd) SetCgroupArgsForCall(i int) int {
+	fake.setCgroupMutex.RLock()
+	defer fake.setCgroupMutex.RUnlock()
+	argsForCall := fake.setCgroupArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCmd) SetDir(arg1 string) {
+	fake.setDirMutex.Lock()
+	fake.setDirArgsForCall = append(fake.setDirArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.SetDirStub
+	fake.recordInvocation("SetDir", []interface{}{arg1})
+	fake.setDirMutex.Unlock()
+	if stub != nil {
+		fake.SetDirStub(arg1)
+	}
+}
+
+func (fake *FakeCmd) SetDirCallCount() int {
+	fake.setDirMutex.RLock()
+	defer fake.setDirMutex.RUnlock()
+	return len(fake.setDirArgsForCall)
+}
+
+func (fake *FakeCmd) SetDirCalls(stub func(string)) {
+	fake.setDirMutex.Lock()
+	defer fake.setDirMutex.Unlock()
+	fake.SetDirStub = stub
+}
+
+func (fake *FakeCmd) SetDirArgsForCall(i int) string {
+	fake.setDirMutex.RLock()
+	defer fake.setDirMutex.RUnlock()
+	argsForCall := fake.setDirArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCmd) SetEnv(arg1 []string) {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.setEnvMutex.Lock()
+	fake.setEnvArgsForCall = append(fake.setEnvArgsForCall, struct {
+		arg1 []string
+	}{arg1Copy})
+	stub := fake.SetEnvStub
+	fake.recordInvocation("SetEnv", []interface{}{arg1Copy})
+	fake.setEnvMutex.Unlock()
+	if stub != nil {
+		fake.SetEnvStub(arg1)
+	}
+}
+
+func (fake *FakeCmd) SetEnvCallCount() int {
+	fake.setEnvMutex.RLock()
+	defer fake.setEnvMutex.RUnlock()
+	return len(fake.setEnvArgsForCall)
+}
+
+func (fake *FakeCmd) SetEnvCalls(stub func([]string)) {
+	fake.setEnvMutex.Lock()
+	defer fake.setEnvMutex.Unlock()
+	fake.SetEnvStub = stub
+}
+
+func (fake *FakeCmd) SetEnvArgsForCall(i int) []string {
+	fake.setEnvMutex.RLock()
+	defer fake.setEnvMutex.RUnlock()
+	argsForCall := fake.setEnvArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeCmd) SetOutput(arg1 io.Writer) {
 	fake.setOutputMutex.Lock()
 	fake.setOutputArgsForCall = append(fake.setOutputArgsForCall, struct {
 		arg1 io.Writer
 	}{arg1})
+	stub := fake.SetOutputStub
 	fake.recordInvocation("SetOutput", []interface{}{arg1})
 	fake.setOutputMutex.Unlock()
-	if fake.SetOutputStub != nil {
+	if stub != nil {
 		fake.SetOutputStub(arg1)
 	}
 }
@@ -111,13 +330,241 @@ func (fake *FakeCmd) SetOutputArgsForCall(i int) io.Writer {
 	return argsForCall.arg1
 }
 
+func (fake *FakeCmd) SetProcessGroup(arg1 bool) {
+	fake.setProcessGroupMutex.Lock()
+	fake.setProcessGroupArgsForCall = append(fake.setProcessGroupArgsForCall, struct {
+		arg1 bool
+	}{arg1})
+	stub := fake.SetProcessGroupStub
+	fake.recordInvocation("SetProcessGroup", []interface{}{arg1})
+	fake.setProcessGroupMutex.Unlock()
+	if stub != nil {
+		fake.SetProcessGroupStub(arg1)
+	}
+}
+
+func (fake *FakeCmd) SetProcessGroupCallCount() int {
+	fake.setProcessGroupMutex.RLock()
+	defer fake.setProcessGroupMutex.RUnlock()
+	return len(fake.setProcessGroupArgsForCall)
+}
+
+func (fake *FakeCmd) SetProcessGroupCalls(stub func(bool)) {
+	fake.setProcessGroupMutex.Lock()
+	defer fake.setProcessGroupMutex.Unlock()
+	fake.SetProcessGroupStub = stub
+}
+
+func (fake *FakeCmd) SetProcessGroupArgsForCall(i int) bool {
+	fake.setProcessGroupMutex.RLock()
+	defer fake.setProcessGroupMutex.RUnlock()
+	argsForCall := fake.setProcessGroupArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCmd) SetPty(arg1 bool) {
+	fake.setPtyMutex.Lock()
+	fake.setPtyArgsForCall = append(fake.setPtyArgsForCall, struct {
+		arg1 bool
+	}{arg1})
+	stub := fake.SetPtyStub
+	fake.recordInvocation("SetPty", []interface{}{arg1})
+	fake.setPtyMutex.Unlock()
+	if stub != nil {
+		fake.SetPtyStub(arg1)
+	}
+}
+
+func (fake *FakeCmd) SetPtyCallCount() int {
+	fake.setPtyMutex.RLock()
+	defer fake.setPtyMutex.RUnlock()
+	return len(fake.setPtyArgsForCall)
+}
+
+func (fake *FakeCmd) SetPtyCalls(stub func(bool)) {
+	fake.setPtyMutex.Lock()
+	defer fake.setPtyMutex.Unlock()
+	fake.SetPtyStub = stub
+}
+
+func (fake *FakeCmd) SetPtyArgsForCall(i int) bool {
+	fake.setPtyMutex.RLock()
+	defer fake.setPtyMutex.RUnlock()
+	argsForCall := fake.setPtyArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCmd) SetStdin(arg1 io.Reader) {
+	fake.setStdinMutex.Lock()
+	fake.setStdinArgsForCall = append(fake.setStdinArgsForCall, struct {
+		arg1 io.Reader
+	}{arg1})
+	stub := fake.SetStdinStub
+	fake.recordInvocation("SetStdin", []interface{}{arg1})
+	fake.setStdinMutex.Unlock()
+	if stub != nil {
+		fake.SetStdinStub(arg1)
+	}
+}
+
+func (fake *FakeCmd) SetStdinCallCount() int {
+	fake.setStdinMutex.RLock()
+	defer fake.setStdinMutex.RUnlock()
+	return len(fake.setStdinArgsForCall)
+}
+
+func (fake *FakeCmd) SetStdinCalls(stub func(io.Reader)) {
+	fake.setStdinMutex.Lock()
+	defer fake.setStdinMutex.Unlock()
+	fake.SetStdinStub = stub
+}
+
+func (fake *FakeCmd) SetStdinArgsForCall(i int) io.Reader {
+	fake.setStdinMutex.RLock()
+	defer fake.setStdinMutex.RUnlock()
+	argsForCall := fake.setStdinArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCmd) Signal(arg1 os.Signal) error {
+	fake.signalMutex.Lock()
+	ret, specificReturn := fake.signalReturnsOnCall[len(fake.signalArgsForCall)]
+	fake.signalArgsForCall = append(fake.signalArgsForCall, struct {
+		arg1 os.Signal
+	}{arg1})
+	stub := fake.SignalStub
+	fakeReturns := fake.signalReturns
+	fake.recordInvocation("Signal", []interface{}{arg1})
+	fake.signalMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCmd) SignalCallCount() int {
+	fake.signalMutex.RLock()
+	defer fake.signalMutex.RUnlock()
+	return len(fake.signalArgsForCall)
+}
+
+func (fake *FakeCmd) SignalCalls(stub func(os.Signal) error) {
+	fake.signalMutex.Lock()
+	defer fake.signalMutex.Unlock()
+	fake.SignalStub = stub
+}
+
+func (fake *FakeCmd) SignalArgsForCall(i int) os.Signal {
+	fake.signalMutex.RLock()
+	defer fake.signalMutex.RUnlock()
+	argsForCall := fake.signalArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCmd) SignalReturns(result1 error) {
+	fake.signalMutex.Lock()
+	defer fake.signalMutex.Unlock()
+	fake.SignalStub = nil
+	fake.signalReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCmd) SignalReturnsOnCall(i int, result1 error) {
+	fake.signalMutex.Lock()
+	defer fake.signalMutex.Unlock()
+	fake.SignalStub = nil
+	if fake.signalReturnsOnCall == nil {
+		fake.signalReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.signalReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCmd) Usage() *mrl.Usage {
+	fake.usageMutex.Lock()
+	ret, specificReturn := fake.usageReturnsOnCall[len(fake.usageArgsForCall)]
+	fake.usageArgsForCall = append(fake.usageArgsForCall, struct {
+	}{})
+	stub := fake.UsageStub
+	fakeReturns := fake.usageReturns
+	fake.recordInvocation("Usage", []interface{}{})
+	fake.usageMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCmd) UsageCallCount() int {
+	fake.usageMutex.RLock()
+	defer fake.usageMutex.RUnlock()
+	return len(fake.usageArgsForCall)
+}
+
+func (fake *FakeCmd) UsageCalls(stub func() *mrl.Usage) {
+	fake.usageMutex.Lock()
+	defer fake.usageMutex.Unlock()
+	fake.UsageStub = stub
+}
+
+func (fake *FakeCmd) UsageReturns(result1 *mrl.Usage) {
+	fake.usageMutex.Lock()
+	defer fake.usageMutex.Unlock()
+	fake.UsageStub = nil
+	fake.usageReturns = struct {
+		result1 *mrl.Usage
+	}{result1}
+}
+
+func (fake *FakeCmd) UsageReturnsOnCall(i int, result1 *mrl.Usage) {
+	fake.usageMutex.Lock()
+	defer fake.usageMutex.Unlock()
+	fake.UsageStub = nil
+	if fake.usageReturnsOnCall == nil {
+		fake.usageReturnsOnCall = make(map[int]struct {
+			result1 *mrl.Usage
+		})
+	}
+	fake.usageReturnsOnCall[i] = struct {
+		result1 *mrl.Usage
+	}{result1}
+}
+
 func (fake *FakeCmd) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.pidMutex.RLock()
+	defer fake.pidMutex.RUnlock()
 	fake.runMutex.RLock()
 	defer fake.runMutex.RUnlock()
+	fake.setCgroupMutex.RLock()
+	defer fake.setCgroupMutex.RUnlock()
+	fake.setDirMutex.RLock()
+	defer fake.setDirMutex.RUnlock()
+	fake.setEnvMutex.RLock()
+	defer fake.setEnvMutex.RUnlock()
 	fake.setOutputMutex.RLock()
 	defer fake.setOutputMutex.RUnlock()
+	fake.setProcessGroupMutex.RLock()
+	defer fake.setProcessGroupMutex.RUnlock()
+	fake.setPtyMutex.RLock()
+	defer fake.setPtyMutex.RUnlock()
+	fake.setStdinMutex.RLock()
+	defer fake.setStdinMutex.RUnlock()
+	fake.signalMutex.RLock()
+	defer fake.signalMutex.RUnlock()
+	fake.usageMutex.RLock()
+	defer fake.usageMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,56 @@
+package mrlog
+
+import (
+	"io"
+	"os"
+
+	"github.com/cf-platform-eng/mrlog/pty"
+)
+
+func (cmd *Cmd) SetCgroup(fd int) {
+	cmd.sysProcAttr().UseCgroupFD = true
+	cmd.sysProcAttr().CgroupFD = fd
+}
+
+// runPty runs the process with a pseudo-terminal, sized like mrlog's own
+// terminal, as its controlling terminal, stdout and stderr, and copies the
+// terminal's output to the output writer.
+func (cmd *Cmd) runPty() error {
+	master, tty, err := pty.Open(pty.TerminalSize(os.Stdout, os.Stderr, os.Stdin))
+	if err != nil {
+		return err
+	}
+	defer master.Close()
+
+	output := cmd.Cmd.Stdout
+	if output == nil {
+		output = io.Discard
+	}
+	cmd.Cmd.Stdout = tty
+	cmd.Cmd.Stderr = tty
+	// a new session is also a process group of its own
+	cmd.sysProcAttr().Setsid = true
+	cmd.sysProcAttr().Setctty = true
+	cmd.sysProcAttr().Ctty = 1
+	cmd.group = true
+
+	cmd.mutex.Lock()
+	err = cmd.Cmd.Start()
+	cmd.mutex.Unlock()
+	tty.Close()
+	if err != nil {
+		return err
+	}
+
+	defer cmd.forwardSignals()()
+
+	copied := make(chan struct{})
+	go func() {
+		// reading the master fails with EIO once the process has exited
+		io.Copy(output, master)
+		close(copied)
+	}()
+	err = cmd.Cmd.Wait()
+	<-copied
+	return err
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package mrlog
+
+import "errors"
+
+func (cmd *Cmd) SetCgroup(fd int) {}
+
+func (cmd *Cmd) runPty() error {
+	return errors.New("--pty is only supported on Linux")
+}
@@ -5,6 +5,7 @@ package features_test
 
 import (
 	"encoding/json"
+	"os"
 	"os/exec"
 	"regexp"
 	"time"
@@ -40,6 +41,15 @@ var _ = Describe("log a dependency", func() {
 		steps.And("the machine readable dependency log contains provided metadata")
 	})
 
+	Scenario("logging a dependency as JSON", func() {
+		steps.Given("I have the mrlog binary")
+
+		steps.When("I log a dependency with the json format")
+
+		steps.Then("the command exits without error")
+		steps.And("the result is only a JSON line")
+	})
+
 	Scenario("logging a dependency without a name", func() {
 		steps.Given("I have the mrlog binary")
 
@@ -97,6 +107,24 @@ var _ = Describe("log a dependency", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		define.When(`^I log a dependency with the json format$`, func() {
+			logCommand := exec.Command(
+				mrlogPath,
+				"dependency",
+				"--name",
+				"marman",
+				"--version",
+				"1.2.3",
+				"--type",
+				"binary",
+			)
+			logCommand.Env = append(os.Environ(), "MRLOG_FORMAT=json")
+
+			var err error
+			commandSession, err = gexec.Start(logCommand, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
 		define.When(`^I log a dependency without a name$`, func() {
 			logCommand := exec.Command(
 				mrlogPath,
@@ -165,6 +193,21 @@ var _ = Describe("log a dependency", func() {
 			Eventually(commandSession).Should(gexec.Exit(1))
 		})
 
+		define.Then(`^the result is only a JSON line$`, func() {
+			Eventually(commandSession).Should(gexec.Exit())
+			contents := commandSession.Out.Contents()
+			Expect(contents).To(HavePrefix("{"))
+			Expect(contents).To(HaveSuffix("}\n"))
+
+			machineReadable := &struct {
+				Type string `json:"type"`
+				Name string `json:"name"`
+			}{}
+			Expect(json.Unmarshal(contents, machineReadable)).To(Succeed())
+			Expect(machineReadable.Type).To(Equal("binary dependency"))
+			Expect(machineReadable.Name).To(Equal("marman"))
+		})
+
 		define.Then(`^the result contains a human readable log$`, func() {
 			Eventually(commandSession.Out).Should(
 				Say("binary dependency: 'marman' version '1.2.3'"))
@@ -5,10 +5,15 @@ package features_test
 
 import (
 	"encoding/json"
+	"io"
+	"os"
 	"os/exec"
 	"regexp"
+	"syscall"
 	"time"
 
+	"github.com/cf-platform-eng/mrlog/pty"
+
 	. "github.com/bunniesandbeatings/goerkin"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -92,14 +97,79 @@ var _ = Describe("log section boundaries", func() {
 			steps.And("the result contains output from the failed command")
 			steps.And("the result contains human and machine readable successful section end line with failed message")
 		})
+		Scenario("section with a pseudo-terminal", func() {
+			steps.Given("I have the mrlog binary")
+			steps.When("I log a section with a pty")
+			steps.Then("the command exits without error")
+			steps.And("the subcommand's output is a terminal")
+		})
+		Scenario("section running a failing shell pipeline", func() {
+			steps.Given("I have the mrlog binary")
+			steps.When("I log a section with a shell pipeline that fails part way")
+			steps.Then("the command exits with 2")
+			steps.And("the result contains output from the failed command")
+			steps.And("the result contains human and machine readable result 2 section end line")
+		})
+		Scenario("section timing out a script whose commands are still running", func() {
+			steps.Given("I have the mrlog binary")
+			steps.When("I log a section with a script that outlives its timeout")
+			steps.Then("the command exits with 124 soon after the timeout")
+		})
+		Scenario("section changing the settings of its terminal", func() {
+			steps.Given("I have the mrlog binary")
+			steps.When("I log a section changing the settings of the terminal it runs in")
+			steps.Then("the subcommand is not stopped by the terminal")
+		})
+		Scenario("section with a timeout changing the settings of its terminal", func() {
+			steps.Given("I have the mrlog binary")
+			steps.When("I log a section with a timeout changing the settings of the terminal it runs in")
+			steps.Then("the subcommand is not stopped by the terminal")
+		})
+		Scenario("section running a shell script without bash", func() {
+			steps.Given("I have the mrlog binary")
+			steps.When("I log a section with a shell script where bash is not installed")
+			steps.Then("the command exits without error")
+			steps.And("the result contains output from the shell script")
+		})
 	})
 
 	steps.Define(func(define Definitions) {
 		var (
 			commandSession *gexec.Session
 			mrlogPath      string
+			terminal       *Buffer
+			terminalExit   chan int
+			terminalMrlog  *exec.Cmd
 		)
 
+		// runInTerminal runs mrlog with a pseudo-terminal as its controlling
+		// terminal, stdin, stdout and stderr, as it is in an interactive shell
+		runInTerminal := func(args ...string) {
+			master, tty, err := pty.Open(pty.DefaultSize)
+			Expect(err).NotTo(HaveOccurred())
+
+			logCommand := exec.Command(mrlogPath, args...)
+			logCommand.Stdin = tty
+			logCommand.Stdout = tty
+			logCommand.Stderr = tty
+			logCommand.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+			Expect(logCommand.Start()).To(Succeed())
+			tty.Close()
+			terminalMrlog = logCommand
+
+			terminal = NewBuffer()
+			go func() {
+				// reading the master fails once mrlog has exited
+				io.Copy(terminal, master)
+				master.Close()
+			}()
+			terminalExit = make(chan int, 1)
+			go func() {
+				logCommand.Wait()
+				terminalExit <- logCommand.ProcessState.ExitCode()
+			}()
+		}
+
 		define.Given(`^I have the mrlog binary$`, func() {
 			var err error
 			mrlogPath, err = gexec.Build("github.com/cf-platform-eng/mrlog/cmd/mrlog")
@@ -204,6 +274,106 @@ var _ = Describe("log section boundaries", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		define.When(`^I log a section with a shell pipeline that fails part way$`, func() {
+			logCommand := exec.Command(
+				mrlogPath,
+				"section",
+				"--name",
+				"test-section",
+				"--shell",
+				"fixtures/failed-subcommand.sh | cat",
+			)
+			logCommand.Env = append(os.Environ(), "SHELL=/usr/bin/fish")
+
+			var err error
+			commandSession, err = gexec.Start(logCommand, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		define.When(`^I log a section with a script that outlives its timeout$`, func() {
+			logCommand := exec.Command(
+				mrlogPath,
+				"section",
+				"--name",
+				"test-section",
+				"--timeout",
+				"1s",
+				"--shell",
+				"sleep 30; echo done",
+			)
+
+			var err error
+			commandSession, err = gexec.Start(logCommand, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		define.Then(`^the command exits with 124 soon after the timeout$`, func() {
+			Eventually(commandSession, 5*time.Second).Should(gexec.Exit(124))
+			Expect(commandSession.Out).To(Say("Section subcommand timed out after 1s\n"))
+		})
+
+		// a stopped mrlog would otherwise be left behind by a failed scenario
+		killTerminalMrlog := func() {
+			if terminalMrlog != nil {
+				terminalMrlog.Process.Kill()
+				terminalMrlog = nil
+			}
+		}
+
+		define.When(`^I log a section changing the settings of the terminal it runs in$`, func() {
+			runInTerminal("section", "--name", "test-section", "--", "sh", "-c", "stty sane </dev/tty; echo done")
+		}, killTerminalMrlog)
+
+		define.When(`^I log a section with a timeout changing the settings of the terminal it runs in$`, func() {
+			runInTerminal("section", "--name", "test-section", "--timeout", "1m", "--", "sh", "-c", "stty sane </dev/tty; echo done")
+		}, killTerminalMrlog)
+
+		define.Then(`^the subcommand is not stopped by the terminal$`, func() {
+			Eventually(terminalExit, 5*time.Second).Should(Receive(Equal(0)))
+			Eventually(terminal).Should(Say(`done\r?\n`))
+			Eventually(terminal).Should(Say("section-end: 'test-section' result: 0"))
+		})
+
+		define.When(`^I log a section with a shell script where bash is not installed$`, func() {
+			logCommand := exec.Command(
+				mrlogPath,
+				"section",
+				"--name",
+				"test-section",
+				"--shell",
+				"echo from sh",
+			)
+			logCommand.Env = append(os.Environ(), "PATH=/nonexistent", "SHELL=/bin/bash")
+
+			var err error
+			commandSession, err = gexec.Start(logCommand, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		define.Then(`^the result contains output from the shell script$`, func() {
+			Eventually(commandSession.Out).Should(Say("from sh\n"))
+		})
+
+		define.When(`^I log a section with a pty$`, func() {
+			logCommand := exec.Command(
+				mrlogPath,
+				"section",
+				"--name",
+				"test-section",
+				"--pty",
+				"--shell",
+				"[ -t 1 ] && echo 'stdout is a terminal'",
+			)
+
+			var err error
+			commandSession, err = gexec.Start(logCommand, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		define.Then(`^the subcommand's output is a terminal$`, func() {
+			Eventually(commandSession.Out).Should(Say("stdout is a terminal\n"))
+		})
+
 		define.When(`^I log a section without a command$`, func() {
 			logCommand := exec.Command(
 				mrlogPath,
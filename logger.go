@@ -0,0 +1,124 @@
+package mrlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	os_exec "os/exec"
+
+	"github.com/cf-platform-eng/mrlog/clock"
+	"github.com/cf-platform-eng/mrlog/dependency"
+	"github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/note"
+	"github.com/cf-platform-eng/mrlog/section"
+)
+
+// Logger writes MRL records from Go programs, formatted exactly as the
+// mrlog commands format them. It is safe for concurrent use, as its Printer
+// writes records one at a time, so concurrent sections interleave whole lines.
+type Logger struct {
+	Printer *mrl.Printer
+	Clock   clock.Clock
+}
+
+// NewLogger returns a Logger writing records in the default human+mrl format
+// to out. A nil clock uses the system clock.
+func NewLogger(out io.Writer, clock clock.Clock) *Logger {
+	if clock == nil {
+		clock = &Clock{}
+	}
+	return &Logger{
+		Printer: &mrl.Printer{Out: out},
+		Clock:   clock,
+	}
+}
+
+// Section logs fn between a section start and a section end. A non-nil
+// error from fn fails the section with the error as its message and is
+// returned. If ctx is already done fn is not called and the section fails
+// with the context's error. If fn panics the section fails before the panic
+// carries on.
+func (l *Logger) Section(ctx context.Context, name string, fn func() error) error {
+	// the section-end record counts the redactions of this section alone,
+	// not those of others logged at the same time
+	printer := *l.Printer
+	printer.Redactor = l.Printer.Redactor.Scope()
+	start := &section.SectionOpt{
+		Section: section.Section{Type: "start", Name: name},
+		Printer: &printer,
+		Clock:   l.Clock,
+	}
+	if err := start.Execute(nil); err != nil {
+		return err
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			l.endSection(&printer, name, fmt.Errorf("panic: %v", recovered))
+			panic(recovered)
+		}
+	}()
+
+	err := ctx.Err()
+	if err == nil {
+		err = fn()
+	}
+	if endErr := l.endSection(&printer, name, err); endErr != nil && err == nil {
+		return endErr
+	}
+	return err
+}
+
+func (l *Logger) endSection(printer *mrl.Printer, name string, err error) error {
+	end := &section.SectionOpt{
+		Section: section.Section{Type: "end", Name: name},
+		Printer: printer,
+		Clock:   l.Clock,
+	}
+	if err != nil {
+		end.Result = ExitCode(err)
+		end.OnFailure = err.Error()
+	}
+	return end.Execute(nil)
+}
+
+// Dependency logs a dependency. Metadata, if any, must be a JSON object.
+func (l *Logger) Dependency(identities dependency.Identities) error {
+	return (&dependency.DependencyOpt{
+		Identities: identities,
+		Printer:    l.Printer,
+		Clock:      l.Clock,
+	}).Execute(nil)
+}
+
+// Note logs a free-form message.
+func (l *Logger) Note(message string) error {
+	return (&note.NoteOpt{
+		Message: message,
+		Printer: l.Printer,
+		Clock:   l.Clock,
+	}).Execute(nil)
+}
+
+// Close closes the sinks of the Logger's Printer.
+func (l *Logger) Close() error {
+	return l.Printer.Close()
+}
+
+// ExitCode returns the section result for an error: 0 for no error, the
+// exit code of a process or section that failed, or 1 for any other error.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitError *os_exec.ExitError
+	if errors.As(err, &exitError) {
+		return exitError.ExitCode()
+	}
+	var sectionError *section.SectionError
+	if errors.As(err, &sectionError) {
+		return sectionError.Retval
+	}
+	return 1
+}
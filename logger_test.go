@@ -0,0 +1,183 @@
+package mrlog_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog"
+	"github.com/cf-platform-eng/mrlog/clock/clockfakes"
+	"github.com/cf-platform-eng/mrlog/dependency"
+	"github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/mrl/mrlfakes"
+	"github.com/cf-platform-eng/mrlog/redact"
+	"github.com/cf-platform-eng/mrlog/section"
+	"github.com/fatih/color"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Logger", func() {
+	var (
+		out    *Buffer
+		logger *mrlog.Logger
+	)
+
+	BeforeEach(func() {
+		color.NoColor = true
+		out = NewBuffer()
+		clock := &clockfakes.FakeClock{}
+		clock.NowReturns(time.Date(1973, 11, 29, 10, 15, 01, 00, time.UTC))
+		logger = mrlog.NewLogger(out, clock)
+	})
+
+	Describe("Section", func() {
+		It("logs a successful section", func() {
+			called := false
+			Expect(logger.Section(context.Background(), "install", func() error {
+				called = true
+				return nil
+			})).To(Succeed())
+
+			Expect(called).To(BeTrue())
+			Expect(string(out.Contents())).To(Equal(
+				`section-start: 'install' MRL:{"type":"section-start","name":"install","time":"1973-11-29T10:15:01Z"}` + "\n" +
+					`section-end: 'install' result: 0 MRL:{"type":"section-end","name":"install","status":"passed","time":"1973-11-29T10:15:01Z"}` + "\n\n"))
+		})
+
+		It("fails the section with the error", func() {
+			err := logger.Section(context.Background(), "install", func() error {
+				return errors.New("disk full")
+			})
+			Expect(err).To(MatchError("disk full"))
+			Expect(out).To(Say(`section-end: 'install' result: 1 message: 'disk full' MRL:{"type":"section-end","name":"install","result":1,"status":"failed","time":"1973-11-29T10:15:01Z","message":"disk full"}`))
+		})
+
+		It("uses the result of a failed section", func() {
+			err := logger.Section(context.Background(), "install", func() error {
+				return &section.SectionError{Retval: 3, Err: errors.New("exit status 3")}
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(out).To(Say(`"result":3`))
+		})
+
+		It("does not run a section when the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err := logger.Section(ctx, "install", func() error {
+				Fail("section should not run")
+				return nil
+			})
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(out).To(Say(`"message":"context canceled"`))
+		})
+
+		It("ends the section when the function panics", func() {
+			Expect(func() {
+				logger.Section(context.Background(), "install", func() error {
+					panic("out of range")
+				})
+			}).To(PanicWith("out of range"))
+			Expect(out).To(Say(`section-end: 'install' result: 1 message: 'panic: out of range'`))
+		})
+
+		It("counts the redactions of each section alone", func() {
+			redactor, err := redact.New([]string{"hunter2-password"}, nil, false)
+			Expect(err).NotTo(HaveOccurred())
+			logger.Printer.Redactor = redactor
+
+			logger.Section(context.Background(), "login", func() error {
+				return errors.New("rejected hunter2-password")
+			})
+			Expect(out).To(Say(`"message":"rejected \[REDACTED\]","redactions":1}`))
+			Expect(logger.Section(context.Background(), "install", func() error { return nil })).To(Succeed())
+			Expect(out).To(Say(`MRL:{"type":"section-end","name":"install","status":"passed","time":"1973-11-29T10:15:01Z"}`))
+		})
+
+		It("returns an error for a section without a name", func() {
+			Expect(logger.Section(context.Background(), "", func() error { return nil })).To(MatchError("missing section name"))
+		})
+	})
+
+	It("logs dependencies", func() {
+		Expect(logger.Dependency(dependency.Identities{Name: "kubectl", Version: "v1.20.2"})).To(Succeed())
+		Expect(out).To(Say(`dependency: 'kubectl' version 'v1.20.2' MRL:{"type":"dependency","version":"v1.20.2","name":"kubectl","metadata":"","time":"1973-11-29T10:15:01Z"}`))
+	})
+
+	It("logs notes", func() {
+		Expect(logger.Note("using the staging cluster")).To(Succeed())
+		Expect(out).To(Say(`note: 'using the staging cluster' MRL:{"type":"note","time":"1973-11-29T10:15:01Z","message":"using the staging cluster"}`))
+	})
+
+	It("writes whole records when used concurrently", func() {
+		logger.Printer.Format = mrl.FormatJSON
+		var wait sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wait.Add(1)
+			go func(i int) {
+				defer wait.Done()
+				defer GinkgoRecover()
+				Expect(logger.Section(context.Background(), fmt.Sprintf("section-%d", i), func() error {
+					return logger.Note(fmt.Sprintf("in section %d", i))
+				})).To(Succeed())
+			}(i)
+		}
+		wait.Wait()
+
+		lines := strings.Split(strings.TrimSuffix(string(out.Contents()), "\n"), "\n")
+		Expect(lines).To(HaveLen(60))
+		for _, line := range lines {
+			_, ok, err := mrl.Parse(line)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		}
+	})
+
+	It("shares the Printer with an slog handler", func() {
+		seq := int64(0)
+		chain := &mrlfakes.FakeChain{}
+		chain.LinkStub = func(record *mrl.MachineReadableLog, encode func() ([]byte, error)) ([]byte, error) {
+			seq++
+			record.Seq = seq
+			return encode()
+		}
+		logger.Printer.Format = mrl.FormatJSON
+		logger.Printer.Chain = chain
+		slogger := slog.New(mrl.NewSlogHandler(logger.Printer, nil))
+
+		var wait sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wait.Add(2)
+			go func(i int) {
+				defer wait.Done()
+				defer GinkgoRecover()
+				Expect(logger.Note(fmt.Sprintf("note %d", i))).To(Succeed())
+			}(i)
+			go func(i int) {
+				defer wait.Done()
+				slogger.Info(fmt.Sprintf("log %d", i))
+			}(i)
+		}
+		wait.Wait()
+
+		lines := strings.Split(strings.TrimSuffix(string(out.Contents()), "\n"), "\n")
+		Expect(lines).To(HaveLen(20))
+		for i, line := range lines {
+			record, ok, err := mrl.Parse(line)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(record.Seq).To(Equal(int64(i + 1)))
+		}
+	})
+
+	It("converts errors to exit codes", func() {
+		Expect(mrlog.ExitCode(nil)).To(Equal(0))
+		Expect(mrlog.ExitCode(errors.New("failed"))).To(Equal(1))
+		Expect(mrlog.ExitCode(fmt.Errorf("wrapped: %w", &section.SectionError{Retval: 124}))).To(Equal(124))
+	})
+})
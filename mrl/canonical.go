@@ -0,0 +1,31 @@
+package mrl
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Canonical returns the canonical form of a JSON record: keys sorted, no
+// insignificant whitespace and numbers kept exactly as written. Fields listed
+// in omit are left out.
+func Canonical(record []byte, omit ...string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(record))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if object, ok := value.(map[string]interface{}); ok {
+		for _, field := range omit {
+			delete(object, field)
+		}
+	}
+
+	var canonical bytes.Buffer
+	encoder := json.NewEncoder(&canonical)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil { // !branch-not-tested
+		return nil, err
+	}
+	return bytes.TrimSuffix(canonical.Bytes(), []byte("\n")), nil
+}
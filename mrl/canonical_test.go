@@ -0,0 +1,26 @@
+package mrl_test
+
+import (
+	"github.com/cf-platform-eng/mrlog/mrl"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Canonical", func() {
+	It("sorts keys, removes whitespace and keeps numbers as written", func() {
+		canonical, err := mrl.Canonical([]byte(`{ "type": "section-end", "result": 1.50, "name": "<install>" }`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(canonical)).To(Equal(`{"name":"<install>","result":1.50,"type":"section-end"}`))
+	})
+
+	It("omits fields", func() {
+		canonical, err := mrl.Canonical([]byte(`{"type":"section-end","sig":"abc"}`), "sig")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(canonical)).To(Equal(`{"type":"section-end"}`))
+	})
+
+	It("rejects invalid JSON", func() {
+		_, err := mrl.Canonical([]byte(`{"type":`))
+		Expect(err).To(HaveOccurred())
+	})
+})
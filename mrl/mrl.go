@@ -4,13 +4,47 @@ import (
 	"time"
 )
 
+// Run identifies the pipeline run that produced a record.
+type Run struct {
+	ID       string `json:"id,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Pipeline string `json:"pipeline,omitempty"`
+	Job      string `json:"job,omitempty"`
+	Build    string `json:"build,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// Statuses of a section, recorded on section-end and section-skip records
+// to tell apart outcomes that the result alone does not.
+const (
+	StatusPassed    = "passed"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+	StatusCancelled = "cancelled"
+	StatusTimeout   = "timeout"
+	StatusWarning   = "warning"
+)
+
 type MachineReadableLog struct {
-	Type     string      `json:"type"`
-	Hash     string      `json:"hash,omitempty"`
-	Version  string      `json:"version,omitempty"`
-	Name     string      `json:"name,omitempty"`
-	Metadata interface{} `json:"metadata,omitempty"`
-	Result   int         `json:"result,omitempty"`
-	Time     time.Time   `json:"time"`
-	Message  string      `json:"message,omitempty"`
+	Type       string      `json:"type"`
+	Hash       string      `json:"hash,omitempty"`
+	Version    string      `json:"version,omitempty"`
+	Name       string      `json:"name,omitempty"`
+	Metadata   interface{} `json:"metadata,omitempty"`
+	Result     int         `json:"result,omitempty"`
+	Status     string      `json:"status,omitempty"`
+	ExitCode   *int        `json:"exit_code,omitempty"`
+	Time       time.Time   `json:"time"`
+	Message    string      `json:"message,omitempty"`
+	Level      string      `json:"level,omitempty"`
+	Script     string      `json:"script,omitempty"`
+	Usage      *Usage      `json:"usage,omitempty"`
+	Elapsed    float64     `json:"elapsed_seconds,omitempty"`
+	PID        int         `json:"pid,omitempty"`
+	Output     int64       `json:"output_bytes,omitempty"`
+	Run        *Run        `json:"run,omitempty"`
+	Redactions int         `json:"redactions,omitempty"`
+	Seq        int64       `json:"seq,omitempty"`
+	PrevHash   string      `json:"prev_hash,omitempty"`
+	Sig        string      `json:"sig,omitempty"`
 }
@@ -0,0 +1,13 @@
+package mrl_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMRL(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MRL Suite")
+}
@@ -0,0 +1,118 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package mrlfakes
+
+import (
+	"sync"
+
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+type FakeChain struct {
+	LinkStub        func(*mrl.MachineReadableLog, func() ([]byte, error)) ([]byte, error)
+	linkMutex       sync.RWMutex
+	linkArgsForCall []struct {
+		arg1 *mrl.MachineReadableLog
+		arg2 func() ([]byte, error)
+	}
+	linkReturns struct {
+		result1 []byte
+		result2 error
+	}
+	linkReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeChain) Link(arg1 *mrl.MachineReadableLog, arg2 func() ([]byte, error)) ([]byte, error) {
+	fake.linkMutex.Lock()
+	ret, specificReturn := fake.linkReturnsOnCall[len(fake.linkArgsForCall)]
+	fake.linkArgsForCall = append(fake.linkArgsForCall, struct {
+		arg1 *mrl.MachineReadableLog
+		arg2 func() ([]byte, error)
+	}{arg1, arg2})
+	stub := fake.LinkStub
+	fakeReturns := fake.linkReturns
+	fake.recordInvocation("Link", []interface{}{arg1, arg2})
+	fake.linkMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeChain) LinkCallCount() int {
+	fake.linkMutex.RLock()
+	defer fake.linkMutex.RUnlock()
+	return len(fake.linkArgsForCall)
+}
+
+func (fake *FakeChain) LinkCalls(stub func(*mrl.MachineReadableLog, func() ([]byte, error)) ([]byte, error)) {
+	fake.linkMutex.Lock()
+	defer fake.linkMutex.Unlock()
+	fake.LinkStub = stub
+}
+
+func (fake *FakeChain) LinkArgsForCall(i int) (*mrl.MachineReadableLog, func() ([]byte, error)) {
+	fake.linkMutex.RLock()
+	defer fake.linkMutex.RUnlock()
+	argsForCall := fake.linkArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeChain) LinkReturns(result1 []byte, result2 error) {
+	fake.linkMutex.Lock()
+	defer fake.linkMutex.Unlock()
+	fake.LinkStub = nil
+	fake.linkReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeChain) LinkReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.linkMutex.Lock()
+	defer fake.linkMutex.Unlock()
+	fake.LinkStub = nil
+	if fake.linkReturnsOnCall == nil {
+		fake.linkReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.linkReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeChain) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.linkMutex.RLock()
+	defer fake.linkMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeChain) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ mrl.Chain = new(FakeChain)
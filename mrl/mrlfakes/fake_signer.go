@@ -0,0 +1,121 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package mrlfakes
+
+import (
+	"sync"
+
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+type FakeSigner struct {
+	SignRecordStub        func([]byte) (string, error)
+	signRecordMutex       sync.RWMutex
+	signRecordArgsForCall []struct {
+		arg1 []byte
+	}
+	signRecordReturns struct {
+		result1 string
+		result2 error
+	}
+	signRecordReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeSigner) SignRecord(arg1 []byte) (string, error) {
+	var arg1Copy []byte
+	if arg1 != nil {
+		arg1Copy = make([]byte, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.signRecordMutex.Lock()
+	ret, specificReturn := fake.signRecordReturnsOnCall[len(fake.signRecordArgsForCall)]
+	fake.signRecordArgsForCall = append(fake.signRecordArgsForCall, struct {
+		arg1 []byte
+	}{arg1Copy})
+	stub := fake.SignRecordStub
+	fakeReturns := fake.signRecordReturns
+	fake.recordInvocation("SignRecord", []interface{}{arg1Copy})
+	fake.signRecordMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSigner) SignRecordCallCount() int {
+	fake.signRecordMutex.RLock()
+	defer fake.signRecordMutex.RUnlock()
+	return len(fake.signRecordArgsForCall)
+}
+
+func (fake *FakeSigner) SignRecordCalls(stub func([]byte) (string, error)) {
+	fake.signRecordMutex.Lock()
+	defer fake.signRecordMutex.Unlock()
+	fake.SignRecordStub = stub
+}
+
+func (fake *FakeSigner) SignRecordArgsForCall(i int) []byte {
+	fake.signRecordMutex.RLock()
+	defer fake.signRecordMutex.RUnlock()
+	argsForCall := fake.signRecordArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSigner) SignRecordReturns(result1 string, result2 error) {
+	fake.signRecordMutex.Lock()
+	defer fake.signRecordMutex.Unlock()
+	fake.SignRecordStub = nil
+	fake.signRecordReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSigner) SignRecordReturnsOnCall(i int, result1 string, result2 error) {
+	fake.signRecordMutex.Lock()
+	defer fake.signRecordMutex.Unlock()
+	fake.SignRecordStub = nil
+	if fake.signRecordReturnsOnCall == nil {
+		fake.signRecordReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.signRecordReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSigner) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.signRecordMutex.RLock()
+	defer fake.signRecordMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeSigner) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ mrl.Signer = new(FakeSigner)
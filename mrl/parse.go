@@ -0,0 +1,56 @@
+package mrl
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const marker = "MRL:"
+
+// Extract returns the JSON portion of a log line containing an MRL record,
+// either following the " MRL:" marker or as a bare JSON line.
+// The second return value is false if the line does not contain a record.
+func Extract(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "{") {
+		return trimmed, true
+	}
+
+	index := strings.Index(line, marker)
+	if index < 0 {
+		return "", false
+	}
+	if index > 0 && line[index-1] != ' ' {
+		return "", false
+	}
+
+	record := strings.TrimSpace(line[index+len(marker):])
+	if !strings.HasPrefix(record, "{") {
+		return "", false
+	}
+	return record, true
+}
+
+// Parse decodes the MRL record contained in a log line.
+// The second return value is false if the line does not contain a record.
+// Bare JSON lines that do not decode into a typed record are not considered
+// records, as they are most likely output from a subcommand.
+func Parse(line string) (*MachineReadableLog, bool, error) {
+	record, ok := Extract(line)
+	if !ok {
+		return nil, false, nil
+	}
+
+	bare := strings.HasPrefix(strings.TrimSpace(line), "{")
+	machineLog := &MachineReadableLog{}
+	if err := json.Unmarshal([]byte(record), machineLog); err != nil {
+		if bare {
+			return nil, false, nil
+		}
+		return nil, true, err
+	}
+	if bare && machineLog.Type == "" {
+		return nil, false, nil
+	}
+	return machineLog, true, nil
+}
@@ -0,0 +1,43 @@
+package mrl_test
+
+import (
+	"github.com/cf-platform-eng/mrlog/mrl"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parse", func() {
+	It("parses hybrid lines", func() {
+		record, ok, err := mrl.Parse(`section-start: 'install' MRL:{"type":"section-start","name":"install","time":"1973-11-29T10:15:01Z"}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(record.Type).To(Equal("section-start"))
+		Expect(record.Name).To(Equal("install"))
+	})
+
+	It("parses JSON lines", func() {
+		record, ok, err := mrl.Parse(`{"type":"section-end","name":"install","result":2,"time":"1973-11-29T10:15:01Z"}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(record.Type).To(Equal("section-end"))
+		Expect(record.Result).To(Equal(2))
+	})
+
+	It("ignores lines without records", func() {
+		_, ok, err := mrl.Parse("This is a successful command")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("ignores JSON output that is not a record", func() {
+		_, ok, err := mrl.Parse(`{"level":"info"}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns an error for malformed records", func() {
+		_, ok, err := mrl.Parse(`section-start: 'install' MRL:{"type":`)
+		Expect(err).To(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+})
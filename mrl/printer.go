@@ -0,0 +1,167 @@
+package mrl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/cf-platform-eng/mrlog/redact"
+)
+
+type Format string
+
+const (
+	FormatHybrid Format = "human+mrl"
+	FormatJSON   Format = "json"
+	FormatHuman  Format = "human"
+)
+
+//go:generate counterfeiter Sink
+type Sink interface {
+	Write(record []byte) error
+}
+
+//go:generate counterfeiter Chain
+type Chain interface {
+	// Link places the record in the chain and returns it encoded
+	Link(record *MachineReadableLog, encode func() ([]byte, error)) ([]byte, error)
+}
+
+//go:generate counterfeiter Signer
+type Signer interface {
+	// SignRecord returns the signature of an encoded record
+	SignRecord(record []byte) (string, error)
+}
+
+// printMutex serializes the records of every Printer. Copies of a Printer,
+// such as those of parallel tasks, share its output and chain, so a record is
+// linked and written under it to keep them in the same order. Sinks must not
+// block while it is held: the forwarding sink delivers in the background.
+var printMutex sync.Mutex
+
+// Printer writes log entries to Out in the configured format. Every command
+// emits its records through a Printer so that the format is handled once.
+// Records are also written to every sink, regardless of the format, are
+// given the run context, if any, have secrets masked by the Redactor, are
+// signed when there is a Signer and, when there is a Chain, are linked to the
+// previous record. Printers are safe for concurrent use.
+type Printer struct {
+	Out      io.Writer
+	Err      io.Writer
+	Format   Format
+	Sinks    []Sink
+	Run      *Run
+	Redactor *redact.Redactor
+	Chain    Chain
+	Signer   Signer
+	// Defer, when set, is given the emission of each record, which links,
+	// signs and writes it, to call later instead of at once, such as to keep
+	// records with output that is buffered. A record only takes its place in
+	// the chain once it is emitted.
+	Defer func(emit func() error) error
+}
+
+// Close closes every sink that needs closing, such as sinks that batch records.
+// Each sink synchronizes its own closing, so other Printers are not held up
+// while the records are delivered.
+func (p *Printer) Close() error {
+	var closeErr error
+	for _, sink := range p.Sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			if err := closer.Close(); err != nil && closeErr == nil {
+				closeErr = err
+			}
+		}
+	}
+	return closeErr
+}
+
+// Output returns the writer for free-form output that is not a record, such
+// as the output of a section subcommand. In JSON mode that is Err, so that
+// Out only ever contains JSON lines.
+func (p *Printer) Output() io.Writer {
+	if p.Format != FormatJSON {
+		return p.Out
+	}
+	if p.Err == nil {
+		return os.Stderr
+	}
+	return p.Err
+}
+
+// Print writes a log entry made of a human readable sentence and its machine
+// readable record, followed by newline.
+func (p *Printer) Print(human string, record *MachineReadableLog, newline string) error {
+	if record.Run == nil {
+		record.Run = p.Run
+	}
+	if p.Redactor.Enabled() {
+		record.Name = p.Redactor.String(record.Name)
+		record.Version = p.Redactor.String(record.Version)
+		record.Message = p.Redactor.String(record.Message)
+		record.Script = p.Redactor.String(record.Script)
+		record.Metadata = p.Redactor.Value(record.Metadata)
+		// the human readable sentence repeats the record fields
+		human = p.Redactor.Quiet(human)
+	}
+
+	if p.Defer != nil {
+		return p.Defer(func() error {
+			return p.emit(human, record, newline)
+		})
+	}
+	return p.emit(human, record, newline)
+}
+
+// emit links, signs and writes a record, one record at a time.
+func (p *Printer) emit(human string, record *MachineReadableLog, newline string) error {
+	printMutex.Lock()
+	defer printMutex.Unlock()
+	encode := func() ([]byte, error) {
+		recordJSON, err := json.Marshal(record)
+		if err != nil || p.Signer == nil {
+			return recordJSON, err
+		}
+		record.Sig, err = p.Signer.SignRecord(recordJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign record: %w", err)
+		}
+		return json.Marshal(record)
+	}
+	var recordJSON []byte
+	var err error
+	if p.Chain != nil {
+		recordJSON, err = p.Chain.Link(record, encode)
+	} else {
+		recordJSON, err = encode()
+	}
+	if err != nil {
+		return err
+	}
+
+	var line string
+	switch p.Format {
+	case FormatHybrid, "":
+		line = fmt.Sprintf("%s MRL:%s%s", human, recordJSON, newline)
+	case FormatJSON:
+		line = fmt.Sprintf("%s\n", recordJSON)
+	case FormatHuman:
+		line = human + newline
+	default:
+		return fmt.Errorf("invalid format: %s", p.Format)
+	}
+
+	_, err = io.WriteString(p.Out, line)
+	if err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+
+	for _, sink := range p.Sinks {
+		if err := sink.Write(recordJSON); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package mrl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SlogRecordType is the type of the records written by SlogHandler.
+const SlogRecordType = "log"
+
+type groupedAttr struct {
+	groups []string
+	attr   slog.Attr
+}
+
+// SlogHandler is a slog.Handler that writes log records through a Printer,
+// so that they are picked up with the other MRL records. The level and
+// message map onto the record's level and message fields, top level name
+// and version attributes onto the matching fields, and all other attributes
+// onto metadata, with groups as nested objects.
+//
+//	slog.SetDefault(slog.New(mrl.NewSlogHandler(&mrl.Printer{Out: os.Stderr}, nil)))
+type SlogHandler struct {
+	printer *Printer
+	level   slog.Leveler
+	attrs   []groupedAttr
+	groups  []string
+}
+
+// NewSlogHandler returns a handler for records at or above level, or at or
+// above slog.LevelInfo if level is nil.
+func NewSlogHandler(printer *Printer, level slog.Leveler) *SlogHandler {
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	return &SlogHandler{
+		printer: printer,
+		level:   level,
+	}
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	handler := *h
+	handler.attrs = append([]groupedAttr{}, h.attrs...)
+	for _, attr := range attrs {
+		handler.attrs = append(handler.attrs, groupedAttr{groups: h.groups, attr: attr})
+	}
+	return &handler
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handler := *h
+	handler.groups = append(append([]string{}, h.groups...), name)
+	return &handler
+}
+
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	record := &MachineReadableLog{
+		Type:    SlogRecordType,
+		Level:   r.Level.String(),
+		Message: r.Message,
+		Time:    r.Time,
+	}
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	metadata := map[string]interface{}{}
+	human := &strings.Builder{}
+	fmt.Fprintf(human, "%s: %s", record.Level, r.Message)
+
+	add := func(groups []string, attr slog.Attr) {
+		addAttr(record, metadata, human, groups, attr)
+	}
+	for _, grouped := range h.attrs {
+		add(grouped.groups, grouped.attr)
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		add(h.groups, attr)
+		return true
+	})
+	if len(metadata) > 0 {
+		record.Metadata = metadata
+	}
+
+	return h.printer.Print(human.String(), record, "\n")
+}
+
+func addAttr(record *MachineReadableLog, metadata map[string]interface{}, human *strings.Builder, groups []string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		members := attr.Value.Group()
+		if len(members) == 0 {
+			return
+		}
+		if attr.Key != "" {
+			groups = append(append([]string{}, groups...), attr.Key)
+		}
+		for _, member := range members {
+			addAttr(record, metadata, human, groups, member)
+		}
+		return
+	}
+
+	value := attrValue(attr.Value)
+	fmt.Fprintf(human, " %s=%s", strings.Join(append(append([]string{}, groups...), attr.Key), "."), humanValue(value))
+
+	if len(groups) == 0 {
+		switch attr.Key {
+		case "name":
+			record.Name = fmt.Sprint(value)
+			return
+		case "version":
+			record.Version = fmt.Sprint(value)
+			return
+		}
+	}
+
+	group := metadata
+	for _, name := range groups {
+		nested, ok := group[name].(map[string]interface{})
+		if !ok {
+			nested = map[string]interface{}{}
+			group[name] = nested
+		}
+		group = nested
+	}
+	group[attr.Key] = value
+}
+
+// attrValue converts a value to one that encodes sensibly as JSON.
+func attrValue(value slog.Value) interface{} {
+	switch value.Kind() {
+	case slog.KindDuration:
+		return value.Duration().String()
+	case slog.KindTime:
+		return value.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return err.Error()
+		}
+	}
+	return value.Any()
+}
+
+func humanValue(value interface{}) string {
+	text := fmt.Sprint(value)
+	if text == "" || strings.ContainsAny(text, " \t\n\"=") {
+		return strconv.Quote(text)
+	}
+	return text
+}
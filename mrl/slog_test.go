@@ -0,0 +1,99 @@
+package mrl_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/mrl"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("SlogHandler", func() {
+	var (
+		out     *Buffer
+		printer *mrl.Printer
+		logger  *slog.Logger
+		when    time.Time
+	)
+
+	BeforeEach(func() {
+		out = NewBuffer()
+		printer = &mrl.Printer{Out: out}
+		logger = slog.New(mrl.NewSlogHandler(printer, nil))
+		when = time.Date(1973, 11, 29, 10, 15, 01, 00, time.UTC)
+	})
+
+	log := func(handler slog.Handler, level slog.Level, message string, attrs ...slog.Attr) {
+		record := slog.NewRecord(when, level, message, 0)
+		record.AddAttrs(attrs...)
+		Expect(handler.Handle(context.Background(), record)).To(Succeed())
+	}
+
+	It("writes hybrid lines with the level, message and attributes", func() {
+		log(logger.Handler(), slog.LevelWarn, "disk almost full", slog.Int("free_mb", 12), slog.String("mount", "/var lib"))
+		Expect(string(out.Contents())).To(Equal(
+			`WARN: disk almost full free_mb=12 mount="/var lib" MRL:{"type":"log","metadata":{"free_mb":12,"mount":"/var lib"},"time":"1973-11-29T10:15:01Z","message":"disk almost full","level":"WARN"}` + "\n"))
+	})
+
+	It("writes JSON lines in JSON format", func() {
+		printer.Format = mrl.FormatJSON
+		log(logger.Handler(), slog.LevelInfo, "started")
+		Expect(string(out.Contents())).To(Equal(`{"type":"log","time":"1973-11-29T10:15:01Z","message":"started","level":"INFO"}` + "\n"))
+	})
+
+	It("maps name and version onto the record", func() {
+		log(logger.Handler(), slog.LevelInfo, "installed", slog.String("name", "kubectl"), slog.String("version", "v1.20.2"))
+		Expect(out).To(Say(`MRL:{"type":"log","version":"v1.20.2","name":"kubectl","time"`))
+	})
+
+	It("maps groups onto nested metadata", func() {
+		handler := logger.With("service", "api").WithGroup("request").With("method", "GET").Handler()
+		log(handler, slog.LevelInfo, "handled",
+			slog.Group("response", slog.Int("status", 200), slog.Duration("took", 1500*time.Millisecond)),
+			slog.Any("error", errors.New("slow")))
+
+		Expect(out).To(Say(`INFO: handled service=api request.method=GET request.response.status=200 request.response.took=1.5s request.error=slow `))
+		Expect(out).To(Say(`"metadata":{"request":{"error":"slow","method":"GET","response":{"status":200,"took":"1.5s"}},"service":"api"}`))
+	})
+
+	It("drops empty attributes and groups", func() {
+		log(logger.WithGroup("empty").Handler(), slog.LevelInfo, "nothing", slog.Attr{}, slog.Group("none"))
+		Expect(out).To(Say(`INFO: nothing MRL:{"type":"log","time"`))
+	})
+
+	It("filters by level", func() {
+		handler := mrl.NewSlogHandler(printer, slog.LevelWarn)
+		Expect(handler.Enabled(context.Background(), slog.LevelInfo)).To(BeFalse())
+		Expect(handler.Enabled(context.Background(), slog.LevelError)).To(BeTrue())
+
+		logger.Debug("hidden")
+		Expect(out.Contents()).To(BeEmpty())
+	})
+
+	It("writes whole lines when used concurrently", func() {
+		var wait sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wait.Add(1)
+			go func(i int) {
+				defer wait.Done()
+				logger.With("worker", i).Info(fmt.Sprintf("message %d", i))
+			}(i)
+		}
+		wait.Wait()
+
+		lines := strings.Split(strings.TrimSuffix(string(out.Contents()), "\n"), "\n")
+		Expect(lines).To(HaveLen(20))
+		for _, line := range lines {
+			_, ok, err := mrl.Parse(line)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		}
+	})
+})
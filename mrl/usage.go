@@ -0,0 +1,67 @@
+package mrl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Usage is the resources used by a section subcommand and its waited-for
+// descendants, as reported by getrusage. When the subcommand ran in its own
+// cgroup, Cgroup accounts for every descendant, waited for or not.
+type Usage struct {
+	UserCPU     float64      `json:"user_cpu_seconds"`
+	SystemCPU   float64      `json:"system_cpu_seconds"`
+	MaxRSS      int64        `json:"max_rss_bytes,omitempty"`
+	BlockInput  int64        `json:"block_input_ops,omitempty"`
+	BlockOutput int64        `json:"block_output_ops,omitempty"`
+	Cgroup      *CgroupUsage `json:"cgroup,omitempty"`
+}
+
+// CgroupUsage is the resources used by a cgroup v2 group. Values that the
+// group's enabled controllers do not report are left at zero.
+type CgroupUsage struct {
+	UserCPU      float64 `json:"user_cpu_seconds"`
+	SystemCPU    float64 `json:"system_cpu_seconds"`
+	MemoryPeak   int64   `json:"memory_peak_bytes,omitempty"`
+	IOReadBytes  int64   `json:"io_read_bytes,omitempty"`
+	IOWriteBytes int64   `json:"io_write_bytes,omitempty"`
+}
+
+func bytesString(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	value := float64(bytes) / unit
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f%s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1fTiB", value)
+}
+
+// String returns a compact summary for the human readable section end line,
+// preferring the cgroup accounting when there is one.
+func (u *Usage) String() string {
+	if u.Cgroup != nil {
+		parts := []string{fmt.Sprintf("cpu %.2fs user %.2fs sys", u.Cgroup.UserCPU, u.Cgroup.SystemCPU)}
+		if u.Cgroup.MemoryPeak > 0 {
+			parts = append(parts, "peak memory "+bytesString(u.Cgroup.MemoryPeak))
+		}
+		if u.Cgroup.IOReadBytes > 0 || u.Cgroup.IOWriteBytes > 0 {
+			parts = append(parts, fmt.Sprintf("io %s read %s written", bytesString(u.Cgroup.IOReadBytes), bytesString(u.Cgroup.IOWriteBytes)))
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	parts := []string{fmt.Sprintf("cpu %.2fs user %.2fs sys", u.UserCPU, u.SystemCPU)}
+	if u.MaxRSS > 0 {
+		parts = append(parts, "max rss "+bytesString(u.MaxRSS))
+	}
+	if u.BlockInput > 0 || u.BlockOutput > 0 {
+		parts = append(parts, fmt.Sprintf("blocks %d in %d out", u.BlockInput, u.BlockOutput))
+	}
+	return strings.Join(parts, ", ")
+}
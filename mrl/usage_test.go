@@ -0,0 +1,31 @@
+package mrl_test
+
+import (
+	"github.com/cf-platform-eng/mrlog/mrl"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Usage", func() {
+	It("summarizes rusage", func() {
+		usage := &mrl.Usage{UserCPU: 0.004, SystemCPU: 0}
+		Expect(usage.String()).To(Equal("cpu 0.00s user 0.00s sys"))
+
+		usage = &mrl.Usage{UserCPU: 12.5, SystemCPU: 1, MaxRSS: 512, BlockOutput: 3}
+		Expect(usage.String()).To(Equal("cpu 12.50s user 1.00s sys, max rss 512B, blocks 0 in 3 out"))
+	})
+
+	It("prefers the cgroup accounting", func() {
+		usage := &mrl.Usage{
+			UserCPU: 1,
+			Cgroup: &mrl.CgroupUsage{
+				UserCPU:      2,
+				SystemCPU:    0.25,
+				MemoryPeak:   3 * 1024 * 1024 * 1024,
+				IOReadBytes:  2048,
+				IOWriteBytes: 10,
+			},
+		}
+		Expect(usage.String()).To(Equal("cpu 2.00s user 0.25s sys, peak memory 3.0GiB, io 2.0KiB read 10B written"))
+	})
+})
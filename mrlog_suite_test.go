@@ -0,0 +1,13 @@
+package mrlog_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMrlog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mrlog Suite")
+}
@@ -0,0 +1,23 @@
+package note
+
+import (
+	"fmt"
+
+	"github.com/cf-platform-eng/mrlog/clock"
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+type NoteOpt struct {
+	Message string `long:"message" description:"the note to log" required:"true"`
+	Printer *mrl.Printer
+	Clock   clock.Clock
+}
+
+func (opts *NoteOpt) Execute(args []string) error {
+	machineLog := &mrl.MachineReadableLog{
+		Type:    "note",
+		Message: opts.Message,
+		Time:    opts.Clock.Now(),
+	}
+	return opts.Printer.Print(fmt.Sprintf("note: '%s'", opts.Message), machineLog, "\n")
+}
@@ -0,0 +1,13 @@
+package note_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestNote(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Note Suite")
+}
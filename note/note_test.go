@@ -0,0 +1,29 @@
+package note_test
+
+import (
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/clock/clockfakes"
+	"github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/note"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Note", func() {
+	It("logs the note", func() {
+		out := NewBuffer()
+		clock := &clockfakes.FakeClock{}
+		clock.NowReturns(time.Date(1973, 11, 29, 10, 15, 01, 00, time.UTC))
+
+		opts := &note.NoteOpt{
+			Message: "using the staging cluster",
+			Printer: &mrl.Printer{Out: out},
+			Clock:   clock,
+		}
+		Expect(opts.Execute(nil)).To(Succeed())
+		Expect(string(out.Contents())).To(Equal(
+			`note: 'using the staging cluster' MRL:{"type":"note","time":"1973-11-29T10:15:01Z","message":"using the staging cluster"}` + "\n"))
+	})
+})
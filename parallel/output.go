@@ -0,0 +1,146 @@
+package parallel
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+const (
+	OutputPrefix = "prefix"
+	OutputBuffer = "buffer"
+)
+
+// taskOutput is where a task writes its records and its subcommand's output.
+// Defer, if set, holds back the task's records, and Flush writes anything
+// held back once the task has finished.
+type taskOutput struct {
+	Records io.Writer
+	Output  io.Writer
+	Defer   func(emit func() error) error
+	Flush   func() error
+}
+
+// lineWriter writes whole lines to out under a mutex shared by every task,
+// each starting with prefix, so that the lines of tasks running at the same
+// time do not interleave.
+type lineWriter struct {
+	mutex   *sync.Mutex
+	out     io.Writer
+	prefix  string
+	partial []byte
+	// records end the partial output line that comes before them
+	output *lineWriter
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if w.output != nil {
+		w.output.Flush()
+	}
+	w.partial = append(w.partial, p...)
+	for {
+		end := bytes.IndexByte(w.partial, '\n')
+		if end < 0 {
+			return len(p), nil
+		}
+		if err := w.writeLine(w.partial[:end+1]); err != nil {
+			return 0, err
+		}
+		w.partial = w.partial[end+1:]
+	}
+}
+
+func (w *lineWriter) writeLine(line []byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	_, err := w.out.Write(append([]byte(w.prefix), line...))
+	return err
+}
+
+// Flush ends a partial last line.
+func (w *lineWriter) Flush() {
+	if len(w.partial) > 0 {
+		w.writeLine(append(w.partial, '\n'))
+		w.partial = nil
+	}
+}
+
+// chunk is either output to write to out, or a record to emit.
+type chunk struct {
+	out  io.Writer
+	data []byte
+	emit func() error
+}
+
+// buffer holds all of a task's output and records until it has finished.
+// Records are only emitted, and so linked into any chain, when the buffer
+// is flushed, so that the chain follows the order records are written in.
+type buffer struct {
+	mutex  sync.Mutex
+	chunks []chunk
+}
+
+func (b *buffer) deferRecord(emit func() error) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.chunks = append(b.chunks, chunk{emit: emit})
+	return nil
+}
+
+type bufferWriter struct {
+	buffer *buffer
+	out    io.Writer
+}
+
+func (w *bufferWriter) Write(p []byte) (int, error) {
+	w.buffer.mutex.Lock()
+	defer w.buffer.mutex.Unlock()
+	w.buffer.chunks = append(w.buffer.chunks, chunk{out: w.out, data: append([]byte{}, p...)})
+	return len(p), nil
+}
+
+// flush writes the buffered output and emits the buffered records in the
+// order they were written, holding the shared mutex so that they are not
+// interleaved with other tasks.
+func (b *buffer) flush(mutex *sync.Mutex) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var flushErr error
+	for _, c := range b.chunks {
+		if c.emit == nil {
+			c.out.Write(c.data)
+		} else if err := c.emit(); err != nil && flushErr == nil {
+			flushErr = err
+		}
+	}
+	b.chunks = nil
+	return flushErr
+}
+
+func newTaskOutput(mode string, name string, printer *mrl.Printer, mutex *sync.Mutex) *taskOutput {
+	if mode == OutputBuffer {
+		b := &buffer{}
+		return &taskOutput{
+			Records: printer.Out,
+			Output:  &bufferWriter{buffer: b, out: printer.Output()},
+			Defer:   b.deferRecord,
+			Flush:   func() error { return b.flush(mutex) },
+		}
+	}
+
+	output := &lineWriter{mutex: mutex, out: printer.Output(), prefix: "[" + name + "] "}
+	records := &lineWriter{mutex: mutex, out: printer.Out, output: output}
+	return &taskOutput{
+		Records: records,
+		Output:  output,
+		Flush: func() error {
+			output.Flush()
+			records.Flush()
+			return nil
+		},
+	}
+}
@@ -0,0 +1,270 @@
+package parallel
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/cf-platform-eng/mrlog/clock"
+	"github.com/cf-platform-eng/mrlog/debug"
+	"github.com/cf-platform-eng/mrlog/exec"
+	"github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/section"
+	"github.com/cf-platform-eng/mrlog/trace"
+
+	"github.com/fatih/color"
+)
+
+type ParallelOpt struct {
+	Name         string   `long:"name" description:"name of the section around the tasks" required:"true"`
+	Tasks        []string `long:"task" description:"NAME=SCRIPT, a script run with bash, or /bin/sh without bash, as a section of its own (repeatable)" required:"true"`
+	MaxJobs      int      `long:"max-jobs" description:"run at most this many tasks at once, all of them by default"`
+	FailFast     bool     `long:"fail-fast" description:"stop the other tasks when one fails"`
+	Output       string   `long:"output" choice:"prefix" choice:"buffer" default:"prefix" description:"prefix each line of task output with the task name, or buffer each task's output until it has finished"`
+	OnSuccess    string   `long:"on-success" description:"optional message when every task succeeds"`
+	OnFailure    string   `long:"on-failure" description:"optional message when a task fails"`
+	NoColor      bool     `long:"no-color" env:"MRLOG_NO_COLOR" description:"do not use colors"`
+	ShellOptions string   `long:"shell-options" env:"MRLOG_SHELL_OPTIONS" description:"options for the shell running the task scripts, by default -e -o pipefail for bash and -e for /bin/sh"`
+	ShellPath    string   `long:"shell-path" env:"MRLOG_SHELL" description:"shell running the task scripts, by default bash, or /bin/sh without bash"`
+
+	Printer *mrl.Printer
+	Clock   clock.Clock
+	Exec    exec.Exec
+	Tracer  *trace.Tracer
+	Debug   *debug.Logger
+}
+
+type task struct {
+	name   string
+	script string
+}
+
+// ParseTask splits a --task value into the task name and its script.
+func ParseTask(value string) (string, string, error) {
+	name, script, found := strings.Cut(value, "=")
+	if !found || name == "" || script == "" {
+		return "", "", fmt.Errorf("invalid --task %s: expected NAME=SCRIPT", value)
+	}
+	return name, script, nil
+}
+
+// tracker keeps the commands run by the tasks, so that they can be stopped
+// when a task fails.
+type tracker struct {
+	exec.Exec
+	// group is whether the commands are started in process groups of their
+	// own, to stop the processes they start as well
+	group      bool
+	mutex      sync.Mutex
+	cmds       []exec.Cmd
+	terminated bool
+}
+
+func (t *tracker) Command(command string, arg ...string) exec.Cmd {
+	cmd := t.Exec.Command(command, arg...)
+	if t.group {
+		cmd.SetProcessGroup(true)
+	}
+	return &trackedCmd{Cmd: cmd, tracker: t}
+}
+
+// start records that cmd is about to run, unless the tasks were stopped.
+func (t *tracker) start(cmd exec.Cmd) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.terminated {
+		return false
+	}
+	t.cmds = append(t.cmds, cmd)
+	return true
+}
+
+func (t *tracker) stopped() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.terminated
+}
+
+func (t *tracker) terminate() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.terminated = true
+	for _, cmd := range t.cmds {
+		cmd.Signal(syscall.SIGTERM)
+	}
+}
+
+type trackedCmd struct {
+	exec.Cmd
+	tracker *tracker
+}
+
+func (cmd *trackedCmd) Run() error {
+	if !cmd.tracker.start(cmd.Cmd) {
+		return section.ErrCancelled
+	}
+	err := cmd.Cmd.Run()
+	if err != nil && cmd.tracker.stopped() {
+		return fmt.Errorf("%w: %w", section.ErrCancelled, err)
+	}
+	return err
+}
+
+func (opts *ParallelOpt) section(sectionType string, result int) section.SectionOpt {
+	return section.SectionOpt{
+		Section: section.Section{
+			Type:      sectionType,
+			Name:      opts.Name,
+			Result:    result,
+			OnSuccess: opts.OnSuccess,
+			OnFailure: opts.OnFailure,
+		},
+		Printer: opts.Printer,
+		Clock:   opts.Clock,
+	}
+}
+
+func (opts *ParallelOpt) Execute(args []string) error {
+	if len(args) != 0 {
+		return errors.New("the parallel command takes tasks with --task, not a command")
+	}
+	var tasks []task
+	names := map[string]bool{}
+	for _, value := range opts.Tasks {
+		name, script, err := ParseTask(value)
+		if err != nil {
+			return err
+		}
+		if names[name] {
+			return fmt.Errorf("duplicate task name %s", name)
+		}
+		names[name] = true
+		tasks = append(tasks, task{name, script})
+	}
+	if opts.MaxJobs < 0 {
+		return errors.New("--max-jobs must not be negative")
+	}
+	jobs := opts.MaxJobs
+	if jobs == 0 || jobs > len(tasks) {
+		jobs = len(tasks)
+	}
+
+	if opts.NoColor {
+		color.NoColor = true
+	}
+
+	start := opts.section("start", 0)
+	if err := start.Execute(nil); err != nil {
+		return err
+	}
+
+	outputMutex := &sync.Mutex{}
+	commands := &tracker{Exec: opts.Exec, group: opts.FailFast}
+
+	var (
+		mutex    sync.Mutex
+		result   int
+		stopped  bool
+		skipped  []string
+		wait     sync.WaitGroup
+		slots    = make(chan struct{}, jobs)
+		firstErr error
+	)
+	for _, t := range tasks {
+		slots <- struct{}{}
+		mutex.Lock()
+		if stopped {
+			skipped = append(skipped, t.name)
+			mutex.Unlock()
+			<-slots
+			continue
+		}
+		mutex.Unlock()
+
+		wait.Add(1)
+		go func(t task) {
+			defer wait.Done()
+			defer func() { <-slots }()
+
+			// the copy shares the printer's sinks and chain, which
+			// Printer.Print keeps to one record at a time
+			printer := *opts.Printer
+			output := newTaskOutput(opts.Output, t.name, opts.Printer, outputMutex)
+			printer.Out = output.Records
+			printer.Defer = output.Defer
+			taskSection := &section.SectionOpt{
+				Section: section.Section{
+					Type:         "section",
+					Name:         t.name,
+					Shell:        t.script,
+					ShellOptions: opts.ShellOptions,
+					ShellPath:    opts.ShellPath,
+				},
+				Printer: &printer,
+				Clock:   opts.Clock,
+				Exec:    commands,
+				Tracer:  opts.Tracer,
+				Debug:   opts.Debug,
+				Output:  output.Output,
+			}
+			opts.Debug.Printf("parallel '%s': starting task '%s'", opts.Name, t.name)
+			err := taskSection.Execute(nil)
+			if flushErr := output.Flush(); flushErr != nil && err == nil {
+				err = flushErr
+			}
+
+			exitCode := 0
+			var sectionError *section.SectionError
+			if errors.As(err, &sectionError) {
+				exitCode = sectionError.Retval
+			} else if err != nil {
+				exitCode = -1
+			}
+			opts.Debug.Printf("parallel '%s': task '%s' result %d", opts.Name, t.name, exitCode)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil && sectionError == nil && firstErr == nil {
+				firstErr = fmt.Errorf("task %s: %w", t.name, err)
+			}
+			if exitCode != 0 && result == 0 {
+				result = exitCode
+				if opts.FailFast {
+					stopped = true
+					commands.terminate()
+				}
+			}
+		}(t)
+	}
+	wait.Wait()
+
+	for _, name := range skipped {
+		skip := &section.SectionOpt{
+			Section: section.Section{
+				Type:   "skip",
+				Name:   name,
+				Reason: "not run after a task failed",
+			},
+			Printer: opts.Printer,
+			Clock:   opts.Clock,
+		}
+		if err := skip.Execute(nil); err != nil {
+			return err
+		}
+	}
+
+	end := opts.section("end", result)
+	if err := end.Execute(nil); err != nil {
+		return err
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	if result != 0 {
+		// returning a SectionError to propagate the result as the exit code
+		return &section.SectionError{Retval: result, Err: fmt.Errorf("a task failed with %d", result)}
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package parallel_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/chain"
+	"github.com/cf-platform-eng/mrlog/clock/clockfakes"
+	"github.com/cf-platform-eng/mrlog/exec"
+	"github.com/cf-platform-eng/mrlog/exec/execfakes"
+	"github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/mrl/mrlfakes"
+	"github.com/cf-platform-eng/mrlog/parallel"
+	"github.com/cf-platform-eng/mrlog/redact"
+	"github.com/cf-platform-eng/mrlog/section"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Parallel", func() {
+	var (
+		out     *Buffer
+		context *parallel.ParallelOpt
+		scripts map[string]func(output io.Writer, signals chan os.Signal) error
+		mutex   sync.Mutex
+		cmds    []*execfakes.FakeCmd
+	)
+
+	BeforeEach(func() {
+		out = NewBuffer()
+		clock := &clockfakes.FakeClock{}
+		clock.NowReturns(time.Date(1973, 11, 29, 10, 15, 01, 00, time.UTC))
+
+		scripts = map[string]func(io.Writer, chan os.Signal) error{}
+		cmds = nil
+		fakeExec := &execfakes.FakeExec{}
+		fakeExec.CommandStub = func(command string, args ...string) exec.Cmd {
+			script := args[len(args)-1]
+			received := make(chan os.Signal, 2)
+
+			cmd := &execfakes.FakeCmd{}
+			cmd.SignalStub = func(sig os.Signal) error {
+				received <- sig
+				return nil
+			}
+			cmd.RunStub = func() error {
+				return scripts[script](cmd.SetOutputArgsForCall(0), received)
+			}
+			mutex.Lock()
+			cmds = append(cmds, cmd)
+			mutex.Unlock()
+			return cmd
+		}
+
+		context = &parallel.ParallelOpt{
+			Name:    "checks",
+			Output:  parallel.OutputPrefix,
+			Printer: &mrl.Printer{Out: out, Format: mrl.FormatHuman},
+			Clock:   clock,
+			Exec:    fakeExec,
+		}
+	})
+
+	It("runs every task as a section within the section", func() {
+		scripts["make lint"] = func(output io.Writer, _ chan os.Signal) error {
+			fmt.Fprint(output, "linted\n")
+			return nil
+		}
+		scripts["make units"] = func(output io.Writer, _ chan os.Signal) error {
+			fmt.Fprint(output, "tested\n")
+			return nil
+		}
+		context.Tasks = []string{"lint=make lint", "unit=make units"}
+		context.OnSuccess = "all good"
+
+		Expect(context.Execute([]string{})).To(Succeed())
+		Expect(out).To(Say("section-start: 'checks'\n"))
+		Expect(string(out.Contents())).To(ContainSubstring("section-start: 'lint'\n[lint] linted\n"))
+		Expect(string(out.Contents())).To(ContainSubstring("section-start: 'unit'\n[unit] tested\n"))
+		Expect(string(out.Contents())).To(ContainSubstring("section-end: 'lint' result: 0"))
+		Expect(string(out.Contents())).To(ContainSubstring("section-end: 'unit' result: 0"))
+		Expect(string(out.Contents())).To(HaveSuffix("section-end: 'checks' result: 0 message: 'all good'\n\n"))
+	})
+
+	It("runs the task scripts with the shell given by --shell-path", func() {
+		scripts["make lint"] = func(io.Writer, chan os.Signal) error {
+			return nil
+		}
+		context.Tasks = []string{"lint=make lint"}
+		context.ShellPath = "/usr/bin/zsh"
+		context.ShellOptions = "-e -u"
+
+		Expect(context.Execute([]string{})).To(Succeed())
+		command, args := context.Exec.(*execfakes.FakeExec).CommandArgsForCall(0)
+		Expect(append([]string{command}, args...)).To(Equal([]string{"/usr/bin/zsh", "-e", "-u", "-c", "make lint"}))
+	})
+
+	It("runs the tasks at the same time", func() {
+		started := make(chan bool)
+		scripts["first"] = func(output io.Writer, _ chan os.Signal) error {
+			<-started
+			return nil
+		}
+		scripts["second"] = func(output io.Writer, _ chan os.Signal) error {
+			started <- true
+			return nil
+		}
+		context.Tasks = []string{"first=first", "second=second"}
+
+		Expect(context.Execute([]string{})).To(Succeed())
+	})
+
+	It("runs at most --max-jobs tasks at once", func() {
+		running, most := 0, 0
+		for _, name := range []string{"a", "b", "c", "d"} {
+			scripts[name] = func(output io.Writer, _ chan os.Signal) error {
+				mutex.Lock()
+				running++
+				if running > most {
+					most = running
+				}
+				mutex.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+				return nil
+			}
+		}
+		context.Tasks = []string{"a=a", "b=b", "c=c", "d=d"}
+		context.MaxJobs = 2
+
+		Expect(context.Execute([]string{})).To(Succeed())
+		Expect(most).To(Equal(2))
+	})
+
+	It("fails when a task fails, after running the others", func() {
+		scripts["fail"] = func(output io.Writer, _ chan os.Signal) error {
+			return errors.New("failed")
+		}
+		scripts["pass"] = func(output io.Writer, _ chan os.Signal) error {
+			time.Sleep(10 * time.Millisecond)
+			fmt.Fprint(output, "passed\n")
+			return nil
+		}
+		context.Tasks = []string{"fail=fail", "pass=pass"}
+		context.OnFailure = "checks failed"
+
+		err := context.Execute([]string{})
+		var sectionError *section.SectionError
+		Expect(errors.As(err, &sectionError)).To(BeTrue())
+		Expect(sectionError.Retval).To(Equal(-1))
+		Expect(string(out.Contents())).To(ContainSubstring("section-end: 'fail' result: -1"))
+		Expect(string(out.Contents())).To(ContainSubstring("[pass] passed\n"))
+		Expect(string(out.Contents())).To(HaveSuffix("section-end: 'checks' result: -1 message: 'checks failed'\n\n"))
+	})
+
+	Context("fail fast", func() {
+		var slowStarted chan bool
+
+		BeforeEach(func() {
+			context.FailFast = true
+			slowStarted = make(chan bool, 1)
+			scripts["fail"] = func(output io.Writer, _ chan os.Signal) error {
+				return errors.New("failed")
+			}
+			scripts["slow"] = func(output io.Writer, received chan os.Signal) error {
+				slowStarted <- true
+				Expect(<-received).To(Equal(syscall.SIGTERM))
+				return errors.New("signal: terminated")
+			}
+			scripts["later"] = func(output io.Writer, _ chan os.Signal) error {
+				return nil
+			}
+		})
+
+		It("stops the running tasks when a task fails", func() {
+			scripts["fail after slow"] = func(output io.Writer, _ chan os.Signal) error {
+				<-slowStarted
+				return errors.New("failed")
+			}
+			context.Tasks = []string{"slow=slow", "fail=fail after slow"}
+
+			Expect(context.Execute([]string{})).NotTo(Succeed())
+			Expect(string(out.Contents())).To(ContainSubstring("section-end: 'slow' result: -1 status: cancelled"))
+			Expect(string(out.Contents())).To(HaveSuffix("section-end: 'checks' result: -1\n\n"))
+		})
+
+		It("starts the tasks in process groups of their own to stop what they start", func() {
+			context.Tasks = []string{"later=later"}
+
+			Expect(context.Execute([]string{})).To(Succeed())
+			Expect(cmds).To(HaveLen(1))
+			Expect(cmds[0].SetProcessGroupCallCount()).To(Equal(1))
+			Expect(cmds[0].SetProcessGroupArgsForCall(0)).To(BeTrue())
+		})
+
+		It("does not start the remaining tasks", func() {
+			context.Tasks = []string{"fail=fail", "later=later"}
+			context.MaxJobs = 1
+
+			Expect(context.Execute([]string{})).NotTo(Succeed())
+			Expect(out).NotTo(Say("section-start: 'later'"))
+			Expect(string(out.Contents())).To(ContainSubstring("section-skip: 'later' reason: 'not run after a task failed'\n\n"))
+		})
+	})
+
+	It("counts the redactions of each task in its own section", func() {
+		redactor, err := redact.New([]string{"hunter2-password"}, nil, false)
+		Expect(err).NotTo(HaveOccurred())
+		context.Printer.Redactor = redactor
+		context.Printer.Format = mrl.FormatHybrid
+		scripts["login"] = func(output io.Writer, _ chan os.Signal) error {
+			fmt.Fprint(output, "logging in with hunter2-password\n")
+			return nil
+		}
+		scripts["check"] = func(output io.Writer, _ chan os.Signal) error {
+			fmt.Fprint(output, "clean\n")
+			return nil
+		}
+		context.Tasks = []string{"a=login", "b=check"}
+
+		Expect(context.Execute([]string{})).To(Succeed())
+		Expect(string(out.Contents())).To(MatchRegexp(`MRL:{"type":"section-end","name":"a",[^\n]*"redactions":1}`))
+		Expect(string(out.Contents())).To(MatchRegexp(`MRL:{"type":"section-end","name":"b","status":"passed","time":"[^"]+"}`))
+		Expect(string(out.Contents())).To(MatchRegexp(`MRL:{"type":"section-end","name":"checks",[^\n]*"redactions":1}`))
+	})
+
+	It("leaves the tasks in mrlog's process group without fail fast", func() {
+		scripts["make lint"] = func(output io.Writer, _ chan os.Signal) error {
+			return nil
+		}
+		context.Tasks = []string{"lint=make lint"}
+
+		Expect(context.Execute([]string{})).To(Succeed())
+		Expect(cmds).To(HaveLen(1))
+		Expect(cmds[0].SetProcessGroupCallCount()).To(Equal(0))
+	})
+
+	Context("output", func() {
+		BeforeEach(func() {
+			second := make(chan bool)
+			scripts["first"] = func(output io.Writer, _ chan os.Signal) error {
+				fmt.Fprint(output, "first ")
+				second <- true
+				<-second
+				fmt.Fprint(output, "line\nunfinished")
+				return nil
+			}
+			scripts["second"] = func(output io.Writer, _ chan os.Signal) error {
+				<-second
+				fmt.Fprint(output, "second line\n")
+				second <- true
+				return nil
+			}
+			context.Tasks = []string{"first=first", "second=second"}
+		})
+
+		It("writes whole lines prefixed with the task name", func() {
+			Expect(context.Execute([]string{})).To(Succeed())
+			Expect(string(out.Contents())).To(ContainSubstring("[second] second line\n"))
+			Expect(string(out.Contents())).To(ContainSubstring("[first] first line\n[first] unfinished\nsection-end: 'first' result: 0"))
+		})
+
+		It("buffers the output of each task until it has finished", func() {
+			context.Output = parallel.OutputBuffer
+			Expect(context.Execute([]string{})).To(Succeed())
+			Expect(string(out.Contents())).To(ContainSubstring("section-start: 'second'\nsecond line\nsection-end: 'second' result: 0\n\n"))
+			Expect(string(out.Contents())).To(ContainSubstring("section-start: 'first'\nfirst line\nunfinishedsection-end: 'first' result: 0\n\n"))
+		})
+
+		It("links the records into the chain in the order they are written", func() {
+			dir, err := os.MkdirTemp("", "mrlog-parallel")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			for _, mode := range []string{parallel.OutputPrefix, parallel.OutputBuffer} {
+				out.Clear()
+				context.Output = mode
+				context.Printer.Format = mrl.FormatHybrid
+				context.Printer.Chain = &chain.Chain{StatePath: filepath.Join(dir, mode+".state")}
+				Expect(context.Execute([]string{})).To(Succeed())
+
+				records, _, err := chain.Verify(bytes.NewReader(out.Contents()))
+				Expect(err).NotTo(HaveOccurred(), mode)
+				Expect(records).To(Equal(int64(6)))
+			}
+		})
+	})
+
+	It("writes the records of every task to the sinks", func() {
+		sink := &mrlfakes.FakeSink{}
+		context.Printer.Sinks = []mrl.Sink{sink}
+		scripts["a"] = func(output io.Writer, _ chan os.Signal) error { return nil }
+		scripts["b"] = func(output io.Writer, _ chan os.Signal) error { return nil }
+		context.Tasks = []string{"a=a", "b=b"}
+
+		Expect(context.Execute([]string{})).To(Succeed())
+		Expect(sink.WriteCallCount()).To(Equal(6))
+	})
+
+	It("rejects invalid tasks", func() {
+		context.Tasks = []string{"lint"}
+		Expect(context.Execute([]string{})).To(MatchError("invalid --task lint: expected NAME=SCRIPT"))
+		context.Tasks = []string{"=make lint"}
+		Expect(context.Execute([]string{})).To(MatchError("invalid --task =make lint: expected NAME=SCRIPT"))
+	})
+
+	It("rejects duplicate task names", func() {
+		context.Tasks = []string{"lint=make lint", "lint=make vet"}
+		Expect(context.Execute([]string{})).To(MatchError("duplicate task name lint"))
+	})
+
+	It("rejects a command", func() {
+		context.Tasks = []string{"lint=make lint"}
+		Expect(context.Execute([]string{"make"})).To(MatchError("the parallel command takes tasks with --task, not a command"))
+	})
+})
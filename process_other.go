@@ -0,0 +1,24 @@
+//go:build !unix
+
+package mrlog
+
+import (
+	"errors"
+	"os"
+)
+
+func (cmd *Cmd) setProcessGroup() {}
+
+func (cmd *Cmd) releaseTerminal() {}
+
+func isTerminal(file *os.File) bool {
+	return false
+}
+
+func signalGroup(pgid int, sig os.Signal) error {
+	return errors.New("process groups are not supported")
+}
+
+func (cmd *Cmd) forwardSignals() func() {
+	return func() {}
+}
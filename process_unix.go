@@ -0,0 +1,107 @@
+//go:build unix
+
+package mrlog
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// foreground is held by the command whose process group has taken the place
+// of mrlog's as the foreground process group of its terminal, as only one
+// of those running at once can.
+var foreground sync.Mutex
+
+func (cmd *Cmd) sysProcAttr() *syscall.SysProcAttr {
+	if cmd.Cmd.SysProcAttr == nil {
+		cmd.Cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	return cmd.Cmd.SysProcAttr
+}
+
+// setProcessGroup starts the process in a process group of its own, so that
+// signals also reach the processes it starts, such as those of a script.
+func (cmd *Cmd) setProcessGroup() {
+	cmd.sysProcAttr().Setpgid = true
+	cmd.group = true
+
+	// a background process group is stopped when it reads the terminal or
+	// changes its settings, as password prompts do, so the process group
+	// takes over the terminal while mrlog has it
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		// mrlog has no terminal
+		return
+	}
+	pgrp, err := unix.IoctlGetInt(int(tty.Fd()), unix.TIOCGPGRP)
+	if err != nil || pgrp != unix.Getpgrp() || !foreground.TryLock() {
+		tty.Close()
+		return
+	}
+	cmd.sysProcAttr().Foreground = true
+	cmd.sysProcAttr().Ctty = int(tty.Fd())
+	cmd.tty = tty
+}
+
+// releaseTerminal makes mrlog's process group the foreground process group
+// of its terminal again, once the process has exited.
+func (cmd *Cmd) releaseTerminal() {
+	if cmd.tty == nil {
+		return
+	}
+	// the terminal stops a background process group taking it back, unless
+	// it ignores SIGTTOU
+	ignored := signal.Ignored(syscall.SIGTTOU)
+	signal.Ignore(syscall.SIGTTOU)
+	unix.IoctlSetPointerInt(int(cmd.tty.Fd()), unix.TIOCSPGRP, unix.Getpgrp())
+	if !ignored {
+		signal.Reset(syscall.SIGTTOU)
+	}
+	cmd.tty.Close()
+	cmd.tty = nil
+	foreground.Unlock()
+}
+
+func isTerminal(file *os.File) bool {
+	_, err := unix.IoctlGetWinsize(int(file.Fd()), unix.TIOCGWINSZ)
+	return err == nil
+}
+
+func signalGroup(pgid int, sig os.Signal) error {
+	signum, ok := sig.(syscall.Signal)
+	if !ok {
+		return errors.New("unsupported signal")
+	}
+	return syscall.Kill(-pgid, signum)
+}
+
+// forwardSignals passes the signals that would stop mrlog on to the process
+// group, which no longer gets them from the terminal, until the returned
+// function is called.
+func (cmd *Cmd) forwardSignals() func() {
+	if !cmd.group {
+		return func() {}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				cmd.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
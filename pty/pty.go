@@ -0,0 +1,10 @@
+package pty
+
+// Size is a terminal window size in characters.
+type Size struct {
+	Rows uint16
+	Cols uint16
+}
+
+// DefaultSize is used when mrlog is not itself running in a terminal.
+var DefaultSize = Size{Rows: 24, Cols: 80}
@@ -0,0 +1,67 @@
+package pty
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// Open allocates a pseudo-terminal of the given size, returning its master
+// and the terminal for the child process. Output post-processing is turned
+// off so that line endings are not rewritten to CRLF.
+func Open(size Size) (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open pty: %w", err)
+	}
+
+	tty, err := open(master, size)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, tty, nil
+}
+
+func open(master *os.File, size Size) (*os.File, error) {
+	fd := int(master.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		return nil, fmt.Errorf("failed to unlock pty: %w", err)
+	}
+	number, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pty number: %w", err)
+	}
+
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pty attributes: %w", err)
+	}
+	termios.Oflag &^= unix.ONLCR
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, termios); err != nil {
+		return nil, fmt.Errorf("failed to set pty attributes: %w", err)
+	}
+	if err := unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, &unix.Winsize{Row: size.Rows, Col: size.Cols}); err != nil {
+		return nil, fmt.Errorf("failed to set pty size: %w", err)
+	}
+
+	tty, err := os.OpenFile("/dev/pts/"+strconv.Itoa(number), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pty terminal: %w", err)
+	}
+	return tty, nil
+}
+
+// TerminalSize returns the window size of the first of files that is a
+// terminal, or DefaultSize.
+func TerminalSize(files ...*os.File) Size {
+	for _, file := range files {
+		winsize, err := unix.IoctlGetWinsize(int(file.Fd()), unix.TIOCGWINSZ)
+		if err == nil && winsize.Row > 0 && winsize.Col > 0 {
+			return Size{Rows: winsize.Row, Cols: winsize.Col}
+		}
+	}
+	return DefaultSize
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package pty
+
+import (
+	"errors"
+	"os"
+)
+
+func Open(size Size) (*os.File, *os.File, error) {
+	return nil, nil, errors.New("pseudo-terminals are only supported on Linux")
+}
+
+func TerminalSize(files ...*os.File) Size {
+	return DefaultSize
+}
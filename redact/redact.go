@@ -0,0 +1,190 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const Mask = "[REDACTED]"
+
+// MinimumLength is the shortest value that is redacted. Shorter values, such
+// as an empty or single digit variable, would mask unrelated output.
+const MinimumLength = 4
+
+// Detectors match common token formats.
+var Detectors = []string{
+	`gh[pousr]_[A-Za-z0-9]{36,}`,
+	`github_pat_[A-Za-z0-9_]{22,}`,
+	`glpat-[A-Za-z0-9_-]{20,}`,
+	`(?:AKIA|ASIA)[0-9A-Z]{16}`,
+	`AIza[0-9A-Za-z_-]{35}`,
+	`xox[abposr]-[A-Za-z0-9-]{10,}`,
+	`npm_[A-Za-z0-9]{36}`,
+	`eyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]+`,
+	// a private key block, or the rest of the text after its header
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----(?s:.*?)(?:-----END [A-Z ]*PRIVATE KEY-----|\z)`,
+}
+
+var (
+	keyBegin = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+	keyEnd   = regexp.MustCompile(`-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// Redactor masks secrets: given values, such as those of environment
+// variables, and anything matching the given patterns. It counts the
+// redactions it makes and is safe for concurrent use.
+type Redactor struct {
+	values   []string
+	patterns []*regexp.Regexp
+	// keyBlocks is whether the lines of private key blocks are masked
+	keyBlocks bool
+
+	mutex sync.Mutex
+	count int
+	// parent also counts the redactions of a scoped Redactor
+	parent *Redactor
+}
+
+func New(values []string, patterns []string, detectors bool) (*Redactor, error) {
+	redactor := &Redactor{}
+	for _, value := range values {
+		if len(value) >= MinimumLength {
+			redactor.values = append(redactor.values, value)
+		}
+	}
+	// longest first, so that a value containing another is masked whole
+	sort.Slice(redactor.values, func(i, j int) bool {
+		return len(redactor.values[i]) > len(redactor.values[j])
+	})
+
+	if detectors {
+		patterns = append(append([]string{}, Detectors...), patterns...)
+		redactor.keyBlocks = true
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		redactor.patterns = append(redactor.patterns, re)
+	}
+	return redactor, nil
+}
+
+// Scope returns a Redactor masking the same secrets that counts its own
+// redactions, such as those of a section, which r counts as well.
+func (r *Redactor) Scope() *Redactor {
+	if r == nil {
+		return nil
+	}
+	return &Redactor{
+		values:    r.values,
+		patterns:  r.patterns,
+		keyBlocks: r.keyBlocks,
+		parent:    r,
+	}
+}
+
+// Enabled reports whether there is anything to redact. A nil Redactor is
+// disabled.
+func (r *Redactor) Enabled() bool {
+	return r != nil && (len(r.values) > 0 || len(r.patterns) > 0)
+}
+
+func (r *Redactor) redact(s string) (string, int) {
+	count := 0
+	for _, value := range r.values {
+		if n := strings.Count(s, value); n > 0 {
+			count += n
+			s = strings.ReplaceAll(s, value, Mask)
+		}
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			if match == Mask {
+				return match
+			}
+			count++
+			return Mask
+		})
+	}
+	return s, count
+}
+
+// String masks secrets in s and counts the redactions.
+func (r *Redactor) String(s string) string {
+	if !r.Enabled() {
+		return s
+	}
+	redacted, count := r.redact(s)
+	if count > 0 {
+		for scope := r; scope != nil; scope = scope.parent {
+			scope.mutex.Lock()
+			scope.count += count
+			scope.mutex.Unlock()
+		}
+	}
+	return redacted
+}
+
+// Quiet masks secrets in s without counting them, for text that repeats
+// values that have already been counted.
+func (r *Redactor) Quiet(s string) string {
+	if !r.Enabled() {
+		return s
+	}
+	redacted, _ := r.redact(s)
+	return redacted
+}
+
+// Count returns the number of redactions made so far.
+func (r *Redactor) Count() int {
+	if r == nil {
+		return 0
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.count
+}
+
+// Value masks secrets in every string within a value destined to be
+// marshalled as JSON.
+func (r *Redactor) Value(value interface{}) interface{} {
+	if !r.Enabled() || value == nil {
+		return value
+	}
+
+	switch typed := value.(type) {
+	case string:
+		return r.String(typed)
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(typed))
+		for key, v := range typed {
+			redacted[key] = r.Value(v)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(typed))
+		for i, v := range typed {
+			redacted[i] = r.Value(v)
+		}
+		return redacted
+	case float64, bool, json.Number:
+		return typed
+	default:
+		// other types are redacted in their generic JSON form
+		encoded, err := json.Marshal(typed)
+		if err != nil { // !branch-not-tested
+			return typed
+		}
+		var generic interface{}
+		if err := json.Unmarshal(encoded, &generic); err != nil { // !branch-not-tested
+			return typed
+		}
+		return r.Value(generic)
+	}
+}
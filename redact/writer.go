@@ -0,0 +1,136 @@
+package redact
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLine bounds how much of an unterminated line is held back.
+const maxLine = 64 * 1024
+
+// IdleFlush is how long an unterminated line, such as a prompt or progress
+// dots, is held back for the rest of a secret before it is written as it is.
+var IdleFlush = 100 * time.Millisecond
+
+// Writer masks secrets in everything written through it. Output is redacted a
+// line at a time, so that secrets split across writes are still masked;
+// carriage returns end a line too, so progress bars keep updating, and an
+// unterminated line is written once there has been no output for IdleFlush,
+// so prompts are not held back. With the
+// detectors, the lines of a private key block are left out up to its END
+// line, as the header is masked.
+type Writer struct {
+	redactor *Redactor
+	out      io.Writer
+
+	mutex     sync.Mutex
+	pending   []byte
+	inKey     bool
+	lastWrite time.Time
+	idle      *time.Timer
+}
+
+func (r *Redactor) Writer(out io.Writer) *Writer {
+	return &Writer{redactor: r, out: out}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.pending = append(w.pending, p...)
+	end := bytes.LastIndexAny(w.pending, "\r\n") + 1
+	if end == 0 && len(w.pending) > maxLine {
+		end = len(w.pending)
+	}
+	if end > 0 {
+		complete := w.pending[:end]
+		w.pending = append([]byte{}, w.pending[end:]...)
+		if _, err := io.WriteString(w.out, w.redact(string(complete))); err != nil {
+			return 0, err
+		}
+	}
+	if len(w.pending) > 0 {
+		w.lastWrite = time.Now()
+		if w.idle == nil {
+			w.idle = time.AfterFunc(IdleFlush, w.flushIdle)
+		} else {
+			w.idle.Reset(IdleFlush)
+		}
+	}
+	return len(p), nil
+}
+
+// flushIdle writes the unterminated line once there has been no output for
+// IdleFlush.
+func (w *Writer) flushIdle() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if idle := time.Since(w.lastWrite); idle < IdleFlush {
+		w.idle.Reset(IdleFlush - idle)
+		return
+	}
+	w.flush()
+}
+
+func (w *Writer) redact(text string) string {
+	if !w.redactor.keyBlocks {
+		return w.redactor.String(text)
+	}
+	redacted := &strings.Builder{}
+	for text != "" {
+		end := strings.IndexAny(text, "\r\n") + 1
+		if end == 0 {
+			end = len(text)
+		}
+		redacted.WriteString(w.redactLine(text[:end]))
+		text = text[end:]
+	}
+	return redacted.String()
+}
+
+// redactLine redacts a line, keeping track of whether it is within a private
+// key block.
+func (w *Writer) redactLine(line string) string {
+	if w.inKey {
+		end := keyEnd.FindStringIndex(line)
+		if end == nil {
+			return ""
+		}
+		w.inKey = false
+		line = line[end[1]:]
+		if strings.TrimRight(line, "\r\n") == "" {
+			return ""
+		}
+	}
+
+	content := strings.TrimRight(line, "\r\n")
+	if begins := keyBegin.FindAllStringIndex(content, -1); len(begins) > 0 {
+		lastBegin := begins[len(begins)-1][0]
+		w.inKey = keyEnd.FindStringIndex(content[lastBegin:]) == nil
+	}
+	return w.redactor.String(content) + line[len(content):]
+}
+
+// Flush writes any unterminated line that is still held back.
+func (w *Writer) Flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.idle != nil {
+		w.idle.Stop()
+	}
+	return w.flush()
+}
+
+func (w *Writer) flush() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	pending := string(w.pending)
+	w.pending = nil
+	_, err := io.WriteString(w.out, w.redact(pending))
+	return err
+}
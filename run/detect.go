@@ -0,0 +1,72 @@
+package run
+
+import (
+	"fmt"
+
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+const RunIDEnv = "MRLOG_RUN_ID"
+
+// Detect returns the run context from the environment: the run ID from
+// MRLOG_RUN_ID and the pipeline, job and build identifiers of a supported CI
+// system. It returns nil when there is nothing to identify the run.
+func Detect(getenv func(string) string) *mrl.Run {
+	run := detectCI(getenv)
+	if run == nil {
+		run = &mrl.Run{}
+	}
+	run.ID = getenv(RunIDEnv)
+
+	if *run == (mrl.Run{}) {
+		return nil
+	}
+	return run
+}
+
+func detectCI(getenv func(string) string) *mrl.Run {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		run := &mrl.Run{
+			Provider: "github-actions",
+			Pipeline: getenv("GITHUB_WORKFLOW"),
+			Job:      getenv("GITHUB_JOB"),
+			Build:    getenv("GITHUB_RUN_ID"),
+		}
+		if getenv("GITHUB_SERVER_URL") != "" && getenv("GITHUB_REPOSITORY") != "" && run.Build != "" {
+			run.URL = fmt.Sprintf("%s/%s/actions/runs/%s", getenv("GITHUB_SERVER_URL"), getenv("GITHUB_REPOSITORY"), run.Build)
+		}
+		return run
+	case getenv("GITLAB_CI") == "true":
+		return &mrl.Run{
+			Provider: "gitlab",
+			Pipeline: getenv("CI_PIPELINE_ID"),
+			Job:      getenv("CI_JOB_NAME"),
+			Build:    getenv("CI_JOB_ID"),
+			URL:      getenv("CI_JOB_URL"),
+		}
+	case getenv("JENKINS_URL") != "":
+		return &mrl.Run{
+			Provider: "jenkins",
+			Pipeline: getenv("JOB_NAME"),
+			Job:      getenv("STAGE_NAME"),
+			Build:    getenv("BUILD_NUMBER"),
+			URL:      getenv("BUILD_URL"),
+		}
+	case getenv("BUILD_PIPELINE_NAME") != "" || getenv("BUILD_ID") != "":
+		run := &mrl.Run{
+			Provider: "concourse",
+			Pipeline: getenv("BUILD_PIPELINE_NAME"),
+			Job:      getenv("BUILD_JOB_NAME"),
+			Build:    getenv("BUILD_NAME"),
+		}
+		if run.Build == "" {
+			run.Build = getenv("BUILD_ID")
+		}
+		if getenv("ATC_EXTERNAL_URL") != "" && getenv("BUILD_ID") != "" {
+			run.URL = fmt.Sprintf("%s/builds/%s", getenv("ATC_EXTERNAL_URL"), getenv("BUILD_ID"))
+		}
+		return run
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package run_test
+
+import (
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/clock/clockfakes"
+	"github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/run"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+func environment(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+var _ = Describe("Detect", func() {
+	It("returns nothing outside of a run", func() {
+		Expect(run.Detect(environment(map[string]string{}))).To(BeNil())
+	})
+
+	It("uses the run ID from the environment", func() {
+		Expect(run.Detect(environment(map[string]string{
+			"MRLOG_RUN_ID": "some-run",
+		}))).To(Equal(&mrl.Run{ID: "some-run"}))
+	})
+
+	It("detects Concourse", func() {
+		Expect(run.Detect(environment(map[string]string{
+			"MRLOG_RUN_ID":        "some-run",
+			"BUILD_ID":            "1234",
+			"BUILD_NAME":          "42",
+			"BUILD_JOB_NAME":      "unit-tests",
+			"BUILD_PIPELINE_NAME": "mrlog",
+			"ATC_EXTERNAL_URL":    "https://ci.example.com",
+		}))).To(Equal(&mrl.Run{
+			ID:       "some-run",
+			Provider: "concourse",
+			Pipeline: "mrlog",
+			Job:      "unit-tests",
+			Build:    "42",
+			URL:      "https://ci.example.com/builds/1234",
+		}))
+	})
+
+	It("detects GitHub Actions", func() {
+		Expect(run.Detect(environment(map[string]string{
+			"GITHUB_ACTIONS":    "true",
+			"GITHUB_WORKFLOW":   "CI",
+			"GITHUB_JOB":        "test",
+			"GITHUB_RUN_ID":     "987",
+			"GITHUB_SERVER_URL": "https://github.com",
+			"GITHUB_REPOSITORY": "cf-platform-eng/mrlog",
+		}))).To(Equal(&mrl.Run{
+			Provider: "github-actions",
+			Pipeline: "CI",
+			Job:      "test",
+			Build:    "987",
+			URL:      "https://github.com/cf-platform-eng/mrlog/actions/runs/987",
+		}))
+	})
+
+	It("detects GitLab", func() {
+		Expect(run.Detect(environment(map[string]string{
+			"GITLAB_CI":      "true",
+			"CI_PIPELINE_ID": "55",
+			"CI_JOB_NAME":    "test",
+			"CI_JOB_ID":      "66",
+			"CI_JOB_URL":     "https://gitlab.example.com/jobs/66",
+		}))).To(Equal(&mrl.Run{
+			Provider: "gitlab",
+			Pipeline: "55",
+			Job:      "test",
+			Build:    "66",
+			URL:      "https://gitlab.example.com/jobs/66",
+		}))
+	})
+
+	It("detects Jenkins before Concourse", func() {
+		Expect(run.Detect(environment(map[string]string{
+			"JENKINS_URL":  "https://jenkins.example.com",
+			"BUILD_ID":     "7",
+			"BUILD_NUMBER": "7",
+			"JOB_NAME":     "mrlog",
+			"STAGE_NAME":   "test",
+			"BUILD_URL":    "https://jenkins.example.com/job/mrlog/7",
+		}))).To(Equal(&mrl.Run{
+			Provider: "jenkins",
+			Pipeline: "mrlog",
+			Job:      "test",
+			Build:    "7",
+			URL:      "https://jenkins.example.com/job/mrlog/7",
+		}))
+	})
+})
+
+var _ = Describe("RunStart", func() {
+	var (
+		out     *Buffer
+		printer *mrl.Printer
+		context *run.RunStartOpt
+	)
+
+	BeforeEach(func() {
+		out = NewBuffer()
+		printer = &mrl.Printer{Out: out}
+
+		clock := &clockfakes.FakeClock{}
+		clock.NowReturns(time.Date(1973, 11, 29, 10, 15, 01, 00, time.UTC))
+
+		context = &run.RunStartOpt{
+			Printer: printer,
+			Clock:   clock,
+			Getenv: environment(map[string]string{
+				"GITLAB_CI":   "true",
+				"CI_JOB_NAME": "test",
+			}),
+		}
+	})
+
+	It("generates a run ID", func() {
+		Expect(run.NewID()).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+		Expect(run.NewID()).NotTo(Equal(run.NewID()))
+	})
+
+	It("prints an export statement with the run-start record as a comment", func() {
+		context.ID = "some-run"
+		Expect(context.Execute([]string{})).To(Succeed())
+		Expect(string(out.Contents())).To(Equal(
+			`export MRLOG_RUN_ID='some-run' # run-start: MRL:{"type":"run-start","time":"1973-11-29T10:15:01Z","run":{"id":"some-run","provider":"gitlab","job":"test"}}` + "\n"))
+	})
+
+	It("rejects IDs that are not safe in a shell statement", func() {
+		context.ID = "a'b"
+		Expect(context.Execute([]string{})).To(MatchError("invalid --id a'b: expected letters, digits, '.', '_' and '-'"))
+		Expect(out.Contents()).To(BeEmpty())
+	})
+
+	It("prints only the export statement in JSON mode, writing the record to the error output", func() {
+		errOut := NewBuffer()
+		printer.Format = mrl.FormatJSON
+		printer.Err = errOut
+		context.ID = "some-run"
+		Expect(context.Execute([]string{})).To(Succeed())
+		Expect(string(out.Contents())).To(Equal("export MRLOG_RUN_ID='some-run'\n"))
+		Expect(string(errOut.Contents())).To(Equal(
+			`{"type":"run-start","time":"1973-11-29T10:15:01Z","run":{"id":"some-run","provider":"gitlab","job":"test"}}` + "\n"))
+	})
+
+	It("attaches the run to later records", func() {
+		Expect(context.Execute([]string{})).To(Succeed())
+		Expect(printer.Run.ID).NotTo(BeEmpty())
+		Expect(printer.Run.Job).To(Equal("test"))
+	})
+})
@@ -0,0 +1,77 @@
+package run
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/cf-platform-eng/mrlog/clock"
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+type RunStartOpt struct {
+	ID      string `long:"id" description:"run ID to use instead of generating one"`
+	Printer *mrl.Printer
+	Clock   clock.Clock
+	Getenv  func(string) string
+}
+
+// validID matches the run IDs that are safe to put in the shell statement
+// run-start prints, unquoted or quoted.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// NewID returns a random version 4 UUID.
+func NewID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil { // !branch-not-tested
+		panic(err)
+	}
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
+
+func (opts *RunStartOpt) Execute(args []string) error {
+	getenv := opts.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+
+	id := opts.ID
+	if id == "" {
+		id = NewID()
+	}
+	if !validID.MatchString(id) {
+		return fmt.Errorf("invalid --id %s: expected letters, digits, '.', '_' and '-'", id)
+	}
+
+	run := detectCI(getenv)
+	if run == nil {
+		run = &mrl.Run{}
+	}
+	run.ID = id
+	opts.Printer.Run = run
+
+	machineLog := &mrl.MachineReadableLog{
+		Type: "run-start",
+		Time: opts.Clock.Now(),
+	}
+
+	// The human readable part is a shell statement and the record a shell
+	// comment, so that the run ID can be exported with
+	// eval "$(mrlog run-start)"
+	export := fmt.Sprintf("export %s='%s'", RunIDEnv, id)
+	if opts.Printer.Format != mrl.FormatJSON {
+		return opts.Printer.Print(export+" # run-start:", machineLog, "\n")
+	}
+
+	// A JSON line cannot be evaluated, so in JSON mode the statement is
+	// the only output and the record goes with the other free-form output.
+	if _, err := fmt.Fprintln(opts.Printer.Out, export); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+	printer := *opts.Printer
+	printer.Out = opts.Printer.Output()
+	return printer.Print("", machineLog, "\n")
+}
@@ -0,0 +1,210 @@
+package section
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cf-platform-eng/mrlog/ansi"
+)
+
+// criteria decide whether a subcommand succeeded from its exit code and its
+// output, for tools whose exit code alone does not tell.
+type criteria struct {
+	mapExit      map[int]int
+	successCodes map[int]bool
+	failOn       *regexp.Regexp
+	successOn    *regexp.Regexp
+	captures     []*regexp.Regexp
+}
+
+func (opts *SectionOpt) criteria() (*criteria, error) {
+	c := &criteria{
+		mapExit:      map[int]int{},
+		successCodes: map[int]bool{},
+	}
+	for _, mapping := range opts.MapExit {
+		from, to, found := strings.Cut(mapping, "=")
+		fromCode, fromErr := strconv.Atoi(strings.TrimSpace(from))
+		toCode, toErr := strconv.Atoi(strings.TrimSpace(to))
+		if !found || fromErr != nil || toErr != nil {
+			return nil, fmt.Errorf("invalid --map-exit %s: expected FROM=TO exit codes", mapping)
+		}
+		c.mapExit[fromCode] = toCode
+	}
+	if opts.SuccessCodes != "" {
+		for _, code := range strings.Split(opts.SuccessCodes, ",") {
+			successCode, err := strconv.Atoi(strings.TrimSpace(code))
+			if err != nil {
+				return nil, fmt.Errorf("invalid --success-codes %s: expected comma separated exit codes", opts.SuccessCodes)
+			}
+			c.successCodes[successCode] = true
+		}
+	}
+
+	var err error
+	if opts.FailOnOutput != "" {
+		if c.failOn, err = regexp.Compile(opts.FailOnOutput); err != nil {
+			return nil, fmt.Errorf("invalid --fail-on-output: %w", err)
+		}
+	}
+	if opts.SuccessOnOutput != "" {
+		if c.successOn, err = regexp.Compile(opts.SuccessOnOutput); err != nil {
+			return nil, fmt.Errorf("invalid --success-on-output: %w", err)
+		}
+	}
+	for _, capture := range opts.Capture {
+		captureRE, err := regexp.Compile(capture)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --capture: %w", err)
+		}
+		c.captures = append(c.captures, captureRE)
+	}
+	return c, nil
+}
+
+func (c *criteria) scansOutput() bool {
+	return c.failOn != nil || c.successOn != nil || len(c.captures) > 0
+}
+
+// result returns the section's result for the subcommand's exit code and
+// what was matched in its output, with the reason when it is not the exit
+// code. The exit code is mapped first, then compared with the success codes,
+// and output matching --fail-on-output fails the section even when output
+// matching --success-on-output would pass it.
+func (c *criteria) result(exitCode int, output *outputMatcher) (int, string) {
+	result := exitCode
+	var reasons []string
+	if mapped, ok := c.mapExit[result]; ok {
+		result = mapped
+		reasons = append(reasons, fmt.Sprintf("mapped to %d by --map-exit", mapped))
+	}
+	if result != 0 && c.successCodes[result] {
+		result = 0
+		reasons = append(reasons, fmt.Sprintf("%d is a success code", exitCode))
+	}
+	if output != nil {
+		if line, matched := output.failed(); matched {
+			if result == 0 {
+				result = 1
+			}
+			reasons = append(reasons, fmt.Sprintf("output matched --fail-on-output: '%s'", line))
+		} else if line, matched := output.succeeded(); matched && result != 0 {
+			result = 0
+			reasons = append(reasons, fmt.Sprintf("output matched --success-on-output: '%s'", line))
+		}
+	}
+	return result, strings.Join(reasons, ", ")
+}
+
+// maxMatchLine bounds how much of an unterminated line is kept for matching.
+const maxMatchLine = 64 * 1024
+
+// outputMatcher passes output through to out, looking for the first lines
+// matching the criteria and keeping their named groups. Escape sequences are
+// ignored when matching, and carriage returns end a line too, so each update
+// of a progress bar is a line of its own. A line longer than maxMatchLine,
+// such as in binary output, is matched as far as it goes.
+type outputMatcher struct {
+	mutex        sync.Mutex
+	out          io.Writer
+	criteria     *criteria
+	partial      []byte
+	failLine     string
+	failMatch    bool
+	successLine  string
+	successMatch bool
+	groups       map[string]string
+	capturedBy   []bool
+}
+
+func newOutputMatcher(out io.Writer, c *criteria) *outputMatcher {
+	return &outputMatcher{
+		out:        out,
+		criteria:   c,
+		groups:     map[string]string{},
+		capturedBy: make([]bool, len(c.captures)),
+	}
+}
+
+// capture keeps the named groups of re in line, returning whether it matched.
+func (m *outputMatcher) capture(re *regexp.Regexp, line string) bool {
+	match := re.FindStringSubmatch(line)
+	if match == nil {
+		return false
+	}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			if _, found := m.groups[name]; !found {
+				m.groups[name] = match[i]
+			}
+		}
+	}
+	return true
+}
+
+func (m *outputMatcher) Write(p []byte) (int, error) {
+	m.mutex.Lock()
+	m.partial = append(m.partial, p...)
+	for {
+		end := bytes.IndexAny(m.partial, "\r\n")
+		if end < 0 {
+			break
+		}
+		m.match(string(m.partial[:end]))
+		m.partial = m.partial[end+1:]
+	}
+	if len(m.partial) > maxMatchLine {
+		m.match(string(m.partial))
+		m.partial = nil
+	}
+	m.mutex.Unlock()
+	return m.out.Write(p)
+}
+
+func (m *outputMatcher) match(line string) {
+	line = ansi.Strip(strings.TrimSuffix(line, "\r"))
+	if !m.failMatch && m.criteria.failOn != nil && m.capture(m.criteria.failOn, line) {
+		m.failLine, m.failMatch = line, true
+	}
+	if !m.successMatch && m.criteria.successOn != nil && m.capture(m.criteria.successOn, line) {
+		m.successLine, m.successMatch = line, true
+	}
+	for i, re := range m.criteria.captures {
+		if !m.capturedBy[i] {
+			m.capturedBy[i] = m.capture(re, line)
+		}
+	}
+}
+
+// Flush matches a last line without a newline.
+func (m *outputMatcher) Flush() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.partial) > 0 {
+		m.match(string(m.partial))
+		m.partial = nil
+	}
+}
+
+func (m *outputMatcher) failed() (string, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.failLine, m.failMatch
+}
+
+func (m *outputMatcher) succeeded() (string, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.successLine, m.successMatch
+}
+
+func (m *outputMatcher) captured() map[string]string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.groups
+}
@@ -0,0 +1,111 @@
+package section
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	StdinInherit    = "inherit"
+	StdinNull       = "null"
+	stdinFilePrefix = "file:"
+)
+
+// ReadEnvFile reads KEY=VALUE lines from an env file. Blank lines, comments
+// starting with # and an export prefix are ignored, and values may be quoted.
+func ReadEnvFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open env file: %w", err)
+	}
+	defer file.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+		key, value, found := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid env file %s line %d: expected KEY=VALUE", path, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env = append(env, key+"="+value)
+	}
+	if err := scanner.Err(); err != nil { // !branch-not-tested
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	return env, nil
+}
+
+// mergeEnv returns base with the variables in overrides added, replacing
+// any with the same name.
+func mergeEnv(base []string, overrides ...string) []string {
+	merged := append([]string{}, base...)
+	index := map[string]int{}
+	for i, variable := range merged {
+		key, _, _ := strings.Cut(variable, "=")
+		index[key] = i
+	}
+	for _, variable := range overrides {
+		key, _, _ := strings.Cut(variable, "=")
+		if i, ok := index[key]; ok {
+			merged[i] = variable
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, variable)
+	}
+	return merged
+}
+
+// environment returns the environment for the subcommand: mrlog's own
+// environment unless it is cleared, then the env files and the --env
+// variables, in that order.
+func (opts *SectionOpt) environment() ([]string, error) {
+	var env []string
+	if !opts.ClearEnv {
+		env = os.Environ()
+	}
+	for _, path := range opts.EnvFile {
+		variables, err := ReadEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		env = mergeEnv(env, variables...)
+	}
+	for _, variable := range opts.Env {
+		if key, _, found := strings.Cut(variable, "="); !found || key == "" {
+			return nil, fmt.Errorf("invalid --env %s: expected KEY=VALUE", variable)
+		}
+	}
+	return mergeEnv(env, opts.Env...), nil
+}
+
+// stdin returns the subcommand's input and a function to release it.
+func (opts *SectionOpt) stdin() (io.Reader, func(), error) {
+	switch {
+	case opts.Stdin == "" || opts.Stdin == StdinNull:
+		return nil, func() {}, nil
+	case opts.Stdin == StdinInherit:
+		return os.Stdin, func() {}, nil
+	case strings.HasPrefix(opts.Stdin, stdinFilePrefix):
+		file, err := os.Open(strings.TrimPrefix(opts.Stdin, stdinFilePrefix))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open stdin: %w", err)
+		}
+		return file, func() { file.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("invalid --stdin %s: expected inherit, null or file:<path>", opts.Stdin)
+	}
+}
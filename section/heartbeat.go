@@ -0,0 +1,127 @@
+package section
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/clock"
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+// MinimumSilence is the shortest --heartbeat or --stall-timeout, as the
+// output is checked ten times in each.
+var MinimumSilence = time.Second
+
+// validateSilence checks a --heartbeat or --stall-timeout, which is off when
+// it is zero.
+func validateSilence(option string, value time.Duration) error {
+	if value != 0 && value < MinimumSilence {
+		return fmt.Errorf("invalid %s %s: expected at least %s", option, value, MinimumSilence)
+	}
+	return nil
+}
+
+// monitor counts the output of a subcommand and reports when it has been
+// silent for too long.
+type monitor struct {
+	mutex    sync.Mutex
+	out      io.Writer
+	clock    clock.Clock
+	bytes    int64
+	start    time.Time
+	last     time.Time
+	lineOpen bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newMonitor(out io.Writer, clock clock.Clock) *monitor {
+	now := clock.Now()
+	return &monitor{
+		out:   out,
+		clock: clock,
+		start: now,
+		last:  now,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+func (m *monitor) Write(p []byte) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.bytes += int64(len(p))
+	if len(p) > 0 {
+		m.last = m.clock.Now()
+		m.lineOpen = p[len(p)-1] != '\n'
+	}
+	return m.out.Write(p)
+}
+
+// watch calls beat each time the output has been silent for heartbeat since
+// the last output or beat, and stall once it has been silent for
+// stallTimeout. Either interval may be zero to disable it.
+func (m *monitor) watch(heartbeat, stallTimeout time.Duration, beat func(elapsed, silent time.Duration, bytes int64), stall func(silent time.Duration)) {
+	interval := heartbeat
+	if interval == 0 || (stallTimeout > 0 && stallTimeout < interval) {
+		interval = stallTimeout
+	}
+	ticker := time.NewTicker(interval / 10)
+
+	go func() {
+		defer close(m.done)
+		defer ticker.Stop()
+		lastBeat := time.Time{}
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				now := m.clock.Now()
+				m.mutex.Lock()
+				silent := now.Sub(m.last)
+				if stallTimeout > 0 && silent >= stallTimeout {
+					m.mutex.Unlock()
+					stall(silent)
+					return
+				}
+				if heartbeat > 0 && silent >= heartbeat && now.Sub(lastBeat) >= heartbeat {
+					if m.lineOpen {
+						// end the subcommand's partial line so that the
+						// record starts on a line of its own
+						m.out.Write([]byte("\n"))
+						m.lineOpen = false
+					}
+					beat(now.Sub(m.start), silent, m.bytes)
+					lastBeat = now
+				}
+				m.mutex.Unlock()
+			}
+		}
+	}()
+}
+
+// Stop stops watching the output and waits for any heartbeat being written.
+func (m *monitor) Stop() {
+	close(m.stop)
+	<-m.done
+}
+
+func writeHeartbeat(opts SectionOpt, elapsed time.Duration, silent time.Duration, pid int, bytes int64) error {
+	machineLog := &mrl.MachineReadableLog{
+		Type:    "section-heartbeat",
+		Name:    opts.Name,
+		Time:    opts.Clock.Now(),
+		Elapsed: elapsed.Seconds(),
+		PID:     pid,
+		Output:  bytes,
+	}
+	humanReadable := fmt.Sprintf("section-heartbeat: '%s' running for %s, silent for %s, %d bytes of output",
+		opts.Name,
+		elapsed.Round(time.Second),
+		silent.Round(time.Second),
+		bytes)
+	return opts.Printer.Print(humanReadable, machineLog, "\n")
+}
@@ -0,0 +1,111 @@
+package section
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// messageData is what on-success, on-failure and on-exit message templates
+// can refer to, such as {{.Name}} failed with {{.Result}} after {{.Duration}}.
+type messageData struct {
+	Name     string
+	Result   int
+	ExitCode int
+	Status   string
+	Duration time.Duration
+	// Captured holds the named groups of the first output lines matching
+	// --capture, --fail-on-output and --success-on-output
+	Captured map[string]string
+}
+
+// onExit returns the messages given with --on-exit by exit code.
+func (opts *SectionOpt) onExit() (map[int]string, error) {
+	messages := map[int]string{}
+	for _, value := range opts.OnExit {
+		code, message, found := strings.Cut(value, "=")
+		exitCode, err := strconv.Atoi(strings.TrimSpace(code))
+		if !found || err != nil {
+			return nil, fmt.Errorf("invalid --on-exit %s: expected CODE=MESSAGE", value)
+		}
+		messages[exitCode] = message
+	}
+	return messages, nil
+}
+
+func renderMessage(text string, data messageData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("message").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	rendered := &bytes.Buffer{}
+	if err := tmpl.Execute(rendered, data); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// ValidateMessage checks a message template given other than as an option,
+// such as in a step file.
+func ValidateMessage(text string) error {
+	_, err := renderMessage(text, messageData{})
+	return err
+}
+
+// validateMessages checks the message templates before anything is run, so
+// that a mistake in one does not surface only once the section has ended.
+func (opts *SectionOpt) validateMessages() error {
+	onExit, err := opts.onExit()
+	if err != nil {
+		return err
+	}
+	if _, err := renderMessage(opts.OnSuccess, messageData{}); err != nil {
+		return fmt.Errorf("invalid --on-success message: %w", err)
+	}
+	if _, err := renderMessage(opts.OnFailure, messageData{}); err != nil {
+		return fmt.Errorf("invalid --on-failure message: %w", err)
+	}
+	for code, text := range onExit {
+		if _, err := renderMessage(text, messageData{}); err != nil {
+			return fmt.Errorf("invalid --on-exit %d message: %w", code, err)
+		}
+	}
+	return nil
+}
+
+// message returns the message for how the section ended: the --on-exit
+// message for the subcommand's exit code, or else the on-success or
+// on-failure message, with its template filled in.
+func (opts SectionOpt) message(status string) (string, error) {
+	exitCode := opts.Result
+	if opts.exitCode != nil {
+		exitCode = *opts.exitCode
+	}
+
+	onExit, err := opts.onExit()
+	if err != nil {
+		return "", err
+	}
+	text, found := onExit[exitCode]
+	if !found {
+		if opts.Result == 0 {
+			text = opts.OnSuccess
+		} else {
+			text = opts.OnFailure
+		}
+	}
+	return renderMessage(text, messageData{
+		Name:     opts.Name,
+		Result:   opts.Result,
+		ExitCode: exitCode,
+		Status:   status,
+		Duration: opts.duration,
+		Captured: opts.captured,
+	})
+}
@@ -1,40 +1,101 @@
 package section
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	os_exec "os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/cf-platform-eng/mrlog/ansi"
+	"github.com/cf-platform-eng/mrlog/cgroup"
 	"github.com/cf-platform-eng/mrlog/clock"
+	"github.com/cf-platform-eng/mrlog/debug"
+	"github.com/cf-platform-eng/mrlog/environment"
 	"github.com/cf-platform-eng/mrlog/exec"
 	"github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/redact"
+	"github.com/cf-platform-eng/mrlog/trace"
 
 	"github.com/fatih/color"
 )
 
 type Section struct {
-	Type      string
-	Name      string `long:"name" description:"name of the section"`
-	Result    int    `long:"result" description:"exitCode code for section"`
-	OnSuccess string `long:"on-success" description:"optional message for successful subcommand"`
-	OnFailure string `long:"on-failure" description:"optional message for failed subcommand"`
-	NoColor   bool   `long:"no-color" description:"do not use colors"`
+	Type            string
+	Name            string        `long:"name" description:"name of the section"`
+	Result          int           `long:"result" description:"exitCode code for section"`
+	OnSuccess       string        `long:"on-success" description:"optional message for successful subcommand"`
+	OnFailure       string        `long:"on-failure" description:"optional message for failed subcommand"`
+	OnExit          []string      `long:"on-exit" description:"CODE=MESSAGE, the message when the subcommand exits with CODE, instead of --on-success or --on-failure (repeatable)"`
+	NoColor         bool          `long:"no-color" env:"MRLOG_NO_COLOR" description:"do not use colors"`
+	Timeout         time.Duration `long:"timeout" env:"MRLOG_TIMEOUT" description:"stop the subcommand and fail the section after this long, e.g. 10m"`
+	Preset          string        `long:"preset" description:"apply the settings of a named preset from the configuration file"`
+	Shell           string        `long:"shell" description:"run this script with bash, or /bin/sh without bash, instead of a command"`
+	ShellOptions    string        `long:"shell-options" env:"MRLOG_SHELL_OPTIONS" description:"options for the shell running --shell scripts, by default -e -o pipefail for bash and -e for /bin/sh"`
+	ShellPath       string        `long:"shell-path" env:"MRLOG_SHELL" description:"shell running --shell scripts, by default bash, or /bin/sh without bash"`
+	Cwd             string        `long:"cwd" description:"run the subcommand in this directory"`
+	Env             []string      `long:"env" description:"set KEY=VALUE in the subcommand's environment (repeatable)"`
+	EnvFile         []string      `long:"env-file" description:"set the KEY=VALUE lines of this file in the subcommand's environment (repeatable)"`
+	ClearEnv        bool          `long:"clear-env" description:"do not pass mrlog's environment to the subcommand"`
+	Stdin           string        `long:"stdin" default:"null" description:"the subcommand's input: inherit, null or file:<path>"`
+	Cgroup          bool          `long:"cgroup" description:"run the subcommand in its own cgroup to account for the resources of all its descendants (Linux, cgroup v2)"`
+	Pty             bool          `long:"pty" description:"run the subcommand with a pseudo-terminal, so that it keeps its terminal colors and progress output (Linux)"`
+	OutputFile      string        `long:"output-file" description:"also write the subcommand's output to this file"`
+	StripANSI       bool          `long:"strip-ansi" description:"remove ANSI escape sequences, such as colors, from the --output-file copy"`
+	Timestamps      string        `long:"timestamps" choice:"absolute" choice:"relative" choice:"none" default:"none" description:"prefix each line of subcommand output with the time, or the time since the section started"`
+	Status          string        `long:"status" choice:"passed" choice:"failed" choice:"skipped" choice:"cancelled" choice:"timeout" choice:"warning" description:"status of the section for section-end, passed or failed by its result by default"`
+	AllowFailure    bool          `long:"allow-failure" description:"log a failed subcommand with the warning status and exit 0"`
+	SuccessCodes    string        `long:"success-codes" description:"comma separated exit codes of the subcommand that mean success, e.g. 0,2"`
+	MapExit         []string      `long:"map-exit" description:"FROM=TO, treat the subcommand's exit code FROM as TO (repeatable)"`
+	FailOnOutput    string        `long:"fail-on-output" description:"fail the section when a line of subcommand output matches this regular expression"`
+	SuccessOnOutput string        `long:"success-on-output" description:"pass the section when a line of subcommand output matches this regular expression"`
+	Capture         []string      `long:"capture" description:"regular expression whose named groups, from the first line of subcommand output it matches, messages can use as {{.Captured.name}} (repeatable)"`
+	Reason          string        `long:"reason" description:"why the section was skipped, for section-skip"`
+	Heartbeat       time.Duration `long:"heartbeat" description:"log a section-heartbeat record whenever the subcommand has written no output for this long, e.g. 60s"`
+	StallTimeout    time.Duration `long:"stall-timeout" description:"stop the subcommand and fail the section when it has written no output for this long"`
 }
 
 type SectionOpt struct {
 	Section
-	Out   io.Writer
-	Clock clock.Clock
-	Exec  exec.Exec
+	Printer *mrl.Printer
+	Clock   clock.Clock
+	Exec    exec.Exec
+	Tracer  *trace.Tracer
+	Debug   *debug.Logger
+	// Output receives the subcommand's output, instead of Printer.Output()
+	Output io.Writer
+
+	usage    *mrl.Usage
+	exitCode *int
+	duration time.Duration
+	captured map[string]string
 }
 
+// ErrCancelled marks a subcommand stopped by mrlog, rather than one that
+// failed or timed out, so that its section ends as cancelled.
+var ErrCancelled = errors.New("cancelled")
+
 type SectionError struct {
 	Retval int
 	Err    error
 }
 
+// statusColor colors a human readable line by the section's status.
+func statusColor(status string, line string) string {
+	switch status {
+	case mrl.StatusPassed:
+		return color.GreenString(line)
+	case mrl.StatusWarning, mrl.StatusSkipped, mrl.StatusCancelled:
+		return color.YellowString(line)
+	default:
+		return color.RedString(line)
+	}
+}
+
 func writeSection(opts SectionOpt) error {
 	machineLog := &mrl.MachineReadableLog{
 		Name:   opts.Name,
@@ -46,49 +107,62 @@ func writeSection(opts SectionOpt) error {
 	newline := "\n"
 	var humanReadable string
 	if opts.Type == "start" {
+		machineLog.Script = opts.Shell
 		humanReadable = fmt.Sprintf("section-%s: '%s'",
 			opts.Type,
 			opts.Name)
 	} else if opts.Type == "end" {
 		newline = "\n\n"
+		status := opts.Status
+		if status == "" {
+			status = mrl.StatusPassed
+			if opts.Result != 0 {
+				status = mrl.StatusFailed
+			}
+		}
+		machineLog.Status = status
 		message := ""
-		if opts.Result == 0 && opts.OnSuccess != "" {
-			message = fmt.Sprintf(" message: '%s'", opts.OnSuccess)
-			machineLog.Message = opts.OnSuccess
-		} else if opts.Result != 0 && opts.OnFailure != "" {
-			message = fmt.Sprintf(" message: '%s'", opts.OnFailure)
-			machineLog.Message = opts.OnFailure
-		}
-		humanReadable = fmt.Sprintf("section-%s: '%s' result: %d%s",
+		if status != mrl.StatusPassed && status != mrl.StatusFailed {
+			message = fmt.Sprintf(" status: %s", status)
+		}
+		text, err := opts.message(status)
+		if err != nil {
+			return err
+		}
+		if text != "" {
+			message += fmt.Sprintf(" message: '%s'", text)
+			machineLog.Message = text
+		}
+		// redacting the message here includes it in the count of redactions
+		machineLog.Message = opts.Printer.Redactor.String(machineLog.Message)
+		machineLog.Redactions = opts.Printer.Redactor.Count()
+		if opts.usage != nil {
+			machineLog.Usage = opts.usage
+			message += fmt.Sprintf(" usage: %s", opts.usage)
+		}
+		if opts.exitCode != nil {
+			machineLog.ExitCode = opts.exitCode
+			message = fmt.Sprintf(" exit code: %d", *opts.exitCode) + message
+		}
+		humanReadable = statusColor(status, fmt.Sprintf("section-%s: '%s' result: %d%s",
 			opts.Type,
 			opts.Name,
 			opts.Result,
-			message)
-		if opts.Result == 0 {
-			humanReadable = color.GreenString(humanReadable)
-		} else {
-			humanReadable = color.RedString(humanReadable)
+			message))
+	} else if opts.Type == "skip" {
+		newline = "\n\n"
+		machineLog.Status = mrl.StatusSkipped
+		machineLog.Message = opts.Reason
+		humanReadable = fmt.Sprintf("section-skip: '%s'", opts.Name)
+		if opts.Reason != "" {
+			humanReadable += fmt.Sprintf(" reason: '%s'", opts.Reason)
 		}
+		humanReadable = statusColor(mrl.StatusSkipped, humanReadable)
 	} else {
 		return errors.New("invalid section type argument")
 	}
 
-	_, err := fmt.Fprint(opts.Out, humanReadable)
-	if err != nil {
-		return fmt.Errorf("failed to write: %w", err)
-	}
-
-	machineLogJSON, err := json.Marshal(machineLog)
-	if err != nil { // !branch-not-tested
-		return err
-	}
-
-	_, err = fmt.Fprintf(opts.Out, " MRL:%s%s", string(machineLogJSON), newline)
-	if err != nil {
-		return fmt.Errorf("failed to write: %w", err)
-	}
-
-	return nil
+	return opts.Printer.Print(humanReadable, machineLog, newline)
 }
 
 func (e *SectionError) Unwrap() error { return e.Err }
@@ -99,6 +173,31 @@ func (e *SectionError) Error() string {
 	return ""
 }
 
+// DefaultShell returns the shell running scripts unless another is given with
+// --shell-path: bash when it is installed, for its pipefail option, and
+// otherwise /bin/sh. $SHELL is not used, as the user's interactive shell, such
+// as fish, may not take POSIX options.
+func DefaultShell() string {
+	if bash, err := os_exec.LookPath("bash"); err == nil {
+		return bash
+	}
+	return "/bin/sh"
+}
+
+// ShellCommand returns the command that runs script with shell, passing it
+// the space separated options. Without options, bash gets "-e -o pipefail"
+// and other shells, such as dash, get "-e" as they may not have pipefail.
+func ShellCommand(shell string, options string, script string) []string {
+	if options == "" {
+		options = "-e"
+		if filepath.Base(shell) == "bash" {
+			options = "-e -o pipefail"
+		}
+	}
+	command := append([]string{shell}, strings.Fields(options)...)
+	return append(command, "-c", script)
+}
+
 func (opts *SectionOpt) Execute(args []string) error {
 	if opts.Name == "" {
 		return errors.New("missing section name")
@@ -108,39 +207,302 @@ func (opts *SectionOpt) Execute(args []string) error {
 		color.NoColor = true
 	}
 
+	if opts.Type != "section" && opts.Shell != "" {
+		return errors.New("--shell can only be used with the section command")
+	}
+	if opts.Type != "section" && opts.AllowFailure {
+		return errors.New("--allow-failure can only be used with the section command")
+	}
+	if opts.Type != "section" && (opts.SuccessCodes != "" || len(opts.MapExit) > 0 || opts.FailOnOutput != "" || opts.SuccessOnOutput != "") {
+		return errors.New("--success-codes, --map-exit, --fail-on-output and --success-on-output can only be used with the section command")
+	}
+	if opts.Type != "section" && len(opts.Capture) > 0 {
+		return errors.New("--capture can only be used with the section command")
+	}
+	if err := opts.validateMessages(); err != nil {
+		return err
+	}
+	if err := validateSilence("--heartbeat", opts.Heartbeat); err != nil {
+		return err
+	}
+	if err := validateSilence("--stall-timeout", opts.StallTimeout); err != nil {
+		return err
+	}
+	if opts.Type != "end" && opts.Status != "" {
+		return errors.New("--status can only be used with the section-end command")
+	}
+	if opts.Type != "skip" && opts.Reason != "" {
+		return errors.New("--reason can only be used with the section-skip command")
+	}
+
 	if opts.Type == "section" {
+		if opts.Shell != "" {
+			if len(args) != 0 {
+				return errors.New("the section subcommand takes either --shell or a command, not both")
+			}
+			shell := opts.ShellPath
+			if shell == "" {
+				shell = DefaultShell()
+			}
+			args = ShellCommand(shell, opts.ShellOptions, opts.Shell)
+		}
 		if len(args) == 0 {
 			return errors.New("the section subcommand requires a command parameter '-- <command> ...'")
 		}
 
+		env, err := opts.environment()
+		if err != nil {
+			return err
+		}
+		judge, err := opts.criteria()
+		if err != nil {
+			return err
+		}
+		stdin, closeStdin, err := opts.stdin()
+		if err != nil {
+			return err
+		}
+		defer closeStdin()
+
+		if opts.StripANSI && opts.OutputFile == "" {
+			return errors.New("--strip-ansi requires --output-file")
+		}
+		var outputFile *os.File
+		if opts.OutputFile != "" {
+			outputFile, err = os.Create(opts.OutputFile)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer outputFile.Close()
+		}
+
+		// the section-end record counts the redactions of this section
+		// alone, not those of others running in the same process
+		printer := *opts.Printer
+		printer.Redactor = opts.Printer.Redactor.Scope()
+		// debug output is not written through the redactor, so what it
+		// repeats of the section is redacted up front, like env values
+		debugName := printer.Redactor.Quiet(opts.Name)
+
 		sectionOpts := *opts
 		sectionOpts.Type = "start"
+		sectionOpts.Printer = &printer
+		startTime := opts.Clock.Now()
 		if err := writeSection(sectionOpts); err != nil {
 			return err
 		}
 
 		cmd := opts.Exec.Command(args[0], args[1:]...)
-		cmd.SetOutput(opts.Out)
-		err := cmd.Run()
+		output := opts.Output
+		if output == nil {
+			output = opts.Printer.Output()
+		}
+		if outputFile != nil {
+			var outputCopy io.Writer = outputFile
+			if opts.StripANSI {
+				outputCopy = ansi.NewWriter(outputFile)
+			}
+			output = io.MultiWriter(output, outputCopy)
+		}
+		var redacted *redact.Writer
+		if printer.Redactor.Enabled() {
+			redacted = printer.Redactor.Writer(output)
+			output = redacted
+		}
+		cmdOutput := output
+		if opts.Timestamps == TimestampsAbsolute || opts.Timestamps == TimestampsRelative {
+			cmdOutput = newTimestampWriter(cmdOutput, opts.Timestamps, opts.Clock, startTime)
+		}
+		// the matcher sees the subcommand's own lines, without timestamps
+		var matcher *outputMatcher
+		if judge.scansOutput() {
+			matcher = newOutputMatcher(cmdOutput, judge)
+			cmdOutput = matcher
+		}
+		var silence *monitor
+		if opts.Heartbeat > 0 || opts.StallTimeout > 0 {
+			silence = newMonitor(cmdOutput, opts.Clock)
+			cmdOutput = silence
+		}
+		cmd.SetOutput(cmdOutput)
+		cmd.SetStdin(stdin)
+		if opts.Timeout > 0 || opts.StallTimeout > 0 {
+			// stopping the subcommand includes the processes it started
+			cmd.SetProcessGroup(true)
+		}
+		if opts.Pty {
+			cmd.SetPty(true)
+		}
+		if opts.Cwd != "" {
+			cmd.SetDir(opts.Cwd)
+		}
+
+		var span *trace.Span
+		if opts.Tracer.Enabled() {
+			// spans are exported as they are, so they are redacted like records
+			redactor := printer.Redactor
+			span = opts.Tracer.Start(redactor.Quiet(opts.Name), opts.Clock.Now())
+			span.Attributes["mrlog.section.name"] = redactor.Quiet(opts.Name)
+			span.Attributes["process.command"] = redactor.Quiet(args[0])
+			span.Attributes["process.command_args"] = redactor.Quiet(strings.Join(args, " "))
+			env = mergeEnv(env, fmt.Sprintf("%s=%s", trace.TraceParentEnv, span.TraceParent()))
+		}
+		cmd.SetEnv(env)
+
+		if opts.Debug.Enabled() {
+			debugArgs := make([]string, len(args))
+			for i, arg := range args {
+				debugArgs[i] = printer.Redactor.Quiet(arg)
+			}
+			opts.Debug.Printf("section '%s': exec %q", debugName, debugArgs)
+			if opts.Cwd != "" {
+				opts.Debug.Printf("section '%s': cwd %s", debugName, opts.Cwd)
+			}
+			opts.Debug.Printf("section '%s': stdin %s", debugName, opts.Stdin)
+			// values may be secrets, so only those of the variables that
+			// the env command records by default are shown
+			for _, variable := range env {
+				name, value, _ := strings.Cut(variable, "=")
+				if slices.Contains(environment.DefaultAllowed, name) {
+					opts.Debug.Printf("section '%s': env %s=%s", debugName, name, printer.Redactor.Quiet(value))
+				} else {
+					opts.Debug.Printf("section '%s': env %s", debugName, name)
+				}
+			}
+			if opts.Timeout > 0 {
+				opts.Debug.Printf("section '%s': timeout %s", debugName, opts.Timeout)
+			}
+			if opts.Heartbeat > 0 {
+				opts.Debug.Printf("section '%s': heartbeat %s", debugName, opts.Heartbeat)
+			}
+			if opts.StallTimeout > 0 {
+				opts.Debug.Printf("section '%s': stall timeout %s", debugName, opts.StallTimeout)
+			}
+		}
+
+		var group *cgroup.Group
+		if opts.Cgroup {
+			group, err = cgroup.New(fmt.Sprintf("mrlog-%d", os.Getpid()))
+			if err != nil {
+				fmt.Fprintf(output, "warning: not accounting for the subcommand's resources with a cgroup: %s\n", err)
+			} else {
+				opts.Debug.Printf("section '%s': cgroup %s", debugName, group.Path)
+				cmd.SetCgroup(group.FD())
+				defer func() {
+					if err := group.Remove(); err != nil {
+						opts.Debug.Printf("section '%s': failed to remove cgroup: %s", debugName, err)
+					}
+				}()
+			}
+		}
+
+		done := opts.Debug.Time(fmt.Sprintf("section '%s' subcommand", debugName))
+		deadline := startDeadline(cmd, opts.Timeout)
+		if silence != nil {
+			silence.watch(opts.Heartbeat, opts.StallTimeout,
+				func(elapsed, silent time.Duration, bytes int64) {
+					if err := writeHeartbeat(sectionOpts, elapsed, silent, cmd.Pid(), bytes); err != nil {
+						opts.Debug.Printf("section '%s': failed to write heartbeat: %s", debugName, err)
+					}
+				},
+				func(time.Duration) {
+					deadline.Expire("stalled: no output for " + opts.StallTimeout.String())
+				})
+		}
+		err = cmd.Run()
+		if silence != nil {
+			silence.Stop()
+		}
+		expired := deadline.Stop()
+		done()
+		if redacted != nil {
+			redacted.Flush()
+		}
+		if matcher != nil {
+			matcher.Flush()
+		}
 
 		exitCode := 0
+		status := mrl.StatusPassed
 
 		var sectionError *SectionError
 
-		if err != nil {
+		if expired != "" {
+			exitCode = TimeoutExitCode
+			status = mrl.StatusTimeout
+			fmt.Fprintf(output, "Section subcommand %s\n", expired)
+			sectionError = &SectionError{exitCode, errors.New(expired)}
+		} else {
 			var e *os_exec.ExitError
 			if errors.As(err, &e) {
 				exitCode = e.ExitCode()
-			} else {
+			} else if err != nil {
 				exitCode = -1
 			}
-			fmt.Fprintf(opts.Out, "Section subcommand failed with %d: %s\n", exitCode, err)
-			sectionError = &SectionError{exitCode, err}
+
+			result, reason := exitCode, ""
+			// only a subcommand that ran to completion is judged by the criteria
+			if (err == nil || e != nil) && !errors.Is(err, ErrCancelled) {
+				result, reason = judge.result(exitCode, matcher)
+			}
+			if reason != "" {
+				fmt.Fprintf(output, "Section subcommand exited with %d, %s\n", exitCode, reason)
+				subcommandExit := exitCode
+				sectionOpts.exitCode = &subcommandExit
+			} else if err != nil {
+				fmt.Fprintf(output, "Section subcommand failed with %d: %s\n", exitCode, err)
+			}
+
+			if result != 0 {
+				status = mrl.StatusFailed
+				if errors.Is(err, ErrCancelled) {
+					status = mrl.StatusCancelled
+				}
+				if err == nil {
+					err = errors.New(reason)
+				}
+				sectionError = &SectionError{result, err}
+			}
+			exitCode = result
+		}
+		if sectionError != nil && opts.AllowFailure {
+			status = mrl.StatusWarning
+			sectionError = nil
+		}
+		opts.Debug.Printf("section '%s': result %d status %s", debugName, exitCode, status)
+		// callers running sections, such as run, read how the section ended
+		opts.Result = exitCode
+		opts.Status = status
+		sectionOpts.usage = cmd.Usage()
+		if group != nil {
+			if groupUsage, groupErr := group.Usage(); groupErr != nil {
+				fmt.Fprintf(output, "warning: failed to read the subcommand's cgroup usage: %s\n", groupErr)
+			} else {
+				if sectionOpts.usage == nil {
+					sectionOpts.usage = &mrl.Usage{}
+				}
+				sectionOpts.usage.Cgroup = groupUsage
+			}
 		}
 		sectionOpts.Type = "end"
 		sectionOpts.Result = exitCode
+		sectionOpts.Status = status
+		sectionOpts.duration = opts.Clock.Now().Sub(startTime)
+		if matcher != nil {
+			sectionOpts.captured = matcher.captured()
+		}
 		err = writeSection(sectionOpts)
 
+		if span != nil {
+			span.Attributes["process.exit_code"] = exitCode
+			if exitCode != 0 {
+				span.Failed = true
+				message, _ := sectionOpts.message(status)
+				span.Message = printer.Redactor.Quiet(message)
+			}
+			opts.Tracer.End(span, opts.Clock.Now())
+		}
+
 		if sectionError != nil {
 			// returning sectionError to propagate the resulting non-zero result code
 			return sectionError
@@ -4,13 +4,25 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	os_exec "os/exec"
+	"path/filepath"
 	"regexp"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/cf-platform-eng/mrlog/clock/clockfakes"
+	"github.com/cf-platform-eng/mrlog/debug"
 	"github.com/cf-platform-eng/mrlog/exec/execfakes"
+	mrlpkg "github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/redact"
 	"github.com/cf-platform-eng/mrlog/section"
 	"github.com/cf-platform-eng/mrlog/section/sectionfakes"
+	"github.com/cf-platform-eng/mrlog/trace"
 	"github.com/fatih/color"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -36,9 +48,9 @@ var _ = Describe("Section", func() {
 		exec.CommandReturns(cmd)
 
 		context = &section.SectionOpt{
-			Out:   out,
-			Clock: clock,
-			Exec:  exec,
+			Printer: &mrlpkg.Printer{Out: out},
+			Clock:   clock,
+			Exec:    exec,
 		}
 	})
 
@@ -110,13 +122,69 @@ var _ = Describe("Section", func() {
 		})
 	})
 
+	Context("section end with a status", func() {
+		BeforeEach(func() {
+			context.Result = 1
+			context.Type = "end"
+			context.Name = "install"
+			color.NoColor = false
+		})
+
+		It("records passed or failed by the result", func() {
+			Expect(context.Execute([]string{})).To(Succeed())
+			Expect(out).To(Say(`"result":1,"status":"failed"`))
+		})
+
+		It("records and shows the given status", func() {
+			context.Status = mrlpkg.StatusCancelled
+			Expect(context.Execute([]string{})).To(Succeed())
+			Expect(out.Contents()).To(ContainSubstring(color.YellowString("section-end: 'install' result: 1 status: cancelled")))
+			Expect(out).To(Say(`"result":1,"status":"cancelled"`))
+		})
+
+		It("only accepts a status for section-end", func() {
+			context.Status = mrlpkg.StatusWarning
+			for _, sectionType := range []string{"start", "section", "skip"} {
+				context.Type = sectionType
+				Expect(context.Execute([]string{"command"})).To(MatchError("--status can only be used with the section-end command"))
+			}
+			Expect(out.Contents()).To(BeEmpty())
+		})
+	})
+
+	Context("section skip", func() {
+		BeforeEach(func() {
+			context.Type = "skip"
+			context.Name = "deploy"
+			context.Reason = "not on the main branch"
+			color.NoColor = false
+		})
+
+		It("logs the skipped section and the reason", func() {
+			Expect(context.Execute([]string{})).To(Succeed())
+			Expect(out.Contents()).To(ContainSubstring(color.YellowString("section-skip: 'deploy' reason: 'not on the main branch'")))
+			Expect(out).To(Say(`MRL:{"type":"section-skip","name":"deploy","status":"skipped","time":"1973-11-29T10:15:01Z","message":"not on the main branch"}` + "\n\n"))
+		})
+
+		It("logs a skipped section without a reason", func() {
+			context.Reason = ""
+			Expect(context.Execute([]string{})).To(Succeed())
+			Expect(out).To(Say(`section-skip: 'deploy'.* MRL:{"type":"section-skip","name":"deploy","status":"skipped",`))
+		})
+
+		It("only accepts a reason for skipped sections", func() {
+			context.Type = "end"
+			Expect(context.Execute([]string{})).To(MatchError("--reason can only be used with the section-skip command"))
+		})
+	})
+
 	Context("section output fails", func() {
 		var output *sectionfakes.FakeWriter
 
 		BeforeEach(func() {
 			output = &sectionfakes.FakeWriter{}
 			output.WriteReturns(0, errors.New("write-error"))
-			context.Out = output
+			context.Printer.Out = output
 			context.Name = "install"
 			context.Type = "start"
 		})
@@ -235,6 +303,787 @@ var _ = Describe("Section", func() {
 			})
 		})
 
+		Context("on-exit and templated messages", func() {
+			exitError := func(code int) error {
+				err := os_exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+				Expect(err).To(HaveOccurred())
+				return err
+			}
+
+			BeforeEach(func() {
+				context.OnSuccess = "done"
+				context.OnFailure = "{{.Name}} failed with {{.Result}} after {{.Duration}}"
+				context.OnExit = []string{"3=quota exceeded"}
+				color.NoColor = true
+			})
+
+			It("prints the message for the exit code", func() {
+				cmd.RunReturns(exitError(3))
+				Expect(context.Execute([]string{"command"})).NotTo(Succeed())
+				Expect(out).To(Say(`section-end: 'install' result: 3 message: 'quota exceeded' MRL:.*"message":"quota exceeded"}`))
+			})
+
+			It("fills in the template of the failure message", func() {
+				cmd.RunReturns(exitError(1))
+				Expect(context.Execute([]string{"command"})).NotTo(Succeed())
+				Expect(out).To(Say(`section-end: 'install' result: 1 message: 'install failed with 1 after 0s'`))
+			})
+
+			It("fills in groups captured from the output", func() {
+				context.Capture = []string{`(?P<count>\d+) tests failed`}
+				context.FailOnOutput = `^FAIL (?P<test>\S+)`
+				context.OnFailure = "{{.Captured.count}} failures, first {{.Captured.test}}{{.Captured.missing}}"
+				cmd.RunStub = func() error {
+					fmt.Fprint(cmd.SetOutputArgsForCall(0), "FAIL TestQuota\nFAIL TestInstall\n2 tests failed\n")
+					return nil
+				}
+				Expect(context.Execute([]string{"command"})).NotTo(Succeed())
+				Expect(out).To(Say(`section-end: 'install' result: 1 exit code: 0 message: '2 failures, first TestQuota'`))
+			})
+
+			It("rejects invalid messages before running the subcommand", func() {
+				context.OnExit = []string{"quota exceeded"}
+				Expect(context.Execute([]string{"command"})).To(MatchError("invalid --on-exit quota exceeded: expected CODE=MESSAGE"))
+				context.OnExit = []string{"3={{.Name"}
+				Expect(context.Execute([]string{"command"})).To(MatchError(ContainSubstring("invalid --on-exit 3 message: template: message:1: unclosed action")))
+				context.OnExit = nil
+				context.OnSuccess = "{{.Unknown}}"
+				Expect(context.Execute([]string{"command"})).To(MatchError(ContainSubstring("invalid --on-success message: template: message:1:2: executing")))
+				context.OnSuccess = ""
+				context.Capture = []string{"("}
+				Expect(context.Execute([]string{"command"})).To(MatchError(ContainSubstring("invalid --capture: error parsing regexp")))
+				Expect(cmd.RunCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("status", func() {
+			It("is passed for a successful subcommand", func() {
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(out).To(Say(`section-end: .*"status":"passed"`))
+				Expect(context.Status).To(Equal(mrlpkg.StatusPassed))
+			})
+
+			It("is failed for a failed subcommand", func() {
+				cmd.RunReturns(errors.New("command failed"))
+				Expect(context.Execute([]string{"command"})).NotTo(Succeed())
+				Expect(out).To(Say(`section-end: .*"result":-1,"status":"failed"`))
+				Expect(context.Result).To(Equal(-1))
+				Expect(context.Status).To(Equal(mrlpkg.StatusFailed))
+			})
+
+			It("is cancelled for a subcommand stopped by mrlog", func() {
+				cmd.RunReturns(fmt.Errorf("%w: signal: terminated", section.ErrCancelled))
+				Expect(context.Execute([]string{"command"})).NotTo(Succeed())
+				Expect(out.Contents()).To(ContainSubstring(color.YellowString("section-end: 'install' result: -1 status: cancelled")))
+			})
+
+			It("is warning for an allowed failure, which succeeds", func() {
+				context.AllowFailure = true
+				context.OnFailure = "lint failed"
+				cmd.RunReturns(errors.New("command failed"))
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(out.Contents()).To(ContainSubstring(color.YellowString("section-end: 'install' result: -1 status: warning message: 'lint failed'")))
+				Expect(out).To(Say(`"result":-1,"status":"warning"`))
+			})
+
+			It("only allows failure for the section command", func() {
+				context.Type = "end"
+				context.AllowFailure = true
+				Expect(context.Execute([]string{})).To(MatchError("--allow-failure can only be used with the section command"))
+			})
+		})
+
+		Context("success criteria", func() {
+			exitError := func(code int) error {
+				err := os_exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+				Expect(err).To(HaveOccurred())
+				return err
+			}
+
+			writeOutput := func(output string, err error) {
+				cmd.RunStub = func() error {
+					fmt.Fprint(cmd.SetOutputArgsForCall(0), output)
+					return err
+				}
+			}
+
+			It("passes the section for a success code, recording the exit code", func() {
+				context.SuccessCodes = "0,2"
+				cmd.RunReturns(exitError(2))
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(out).To(Say("Section subcommand exited with 2, 2 is a success code\n"))
+				Expect(out).To(Say(`section-end: 'install' result: 0 exit code: 2.* MRL:{"type":"section-end","name":"install","status":"passed","exit_code":2,`))
+			})
+
+			It("fails the section for other exit codes", func() {
+				context.SuccessCodes = "0,2"
+				cmd.RunReturns(exitError(1))
+				err := context.Execute([]string{"command"})
+				var sectionError *section.SectionError
+				Expect(errors.As(err, &sectionError)).To(BeTrue())
+				Expect(sectionError.Retval).To(Equal(1))
+				Expect(out).To(Say("Section subcommand failed with 1: exit status 1\n"))
+				Expect(out).To(Say(`section-end: 'install' result: 1.* MRL:{"type":"section-end","name":"install","result":1,"status":"failed","time"`))
+			})
+
+			It("maps exit codes", func() {
+				context.MapExit = []string{"1=0", "3=7"}
+				cmd.RunReturns(exitError(3))
+				err := context.Execute([]string{"command"})
+				var sectionError *section.SectionError
+				Expect(errors.As(err, &sectionError)).To(BeTrue())
+				Expect(sectionError.Retval).To(Equal(7))
+				Expect(out).To(Say("Section subcommand exited with 3, mapped to 7 by --map-exit\n"))
+				Expect(out).To(Say(`"result":7,"status":"failed","exit_code":3,`))
+			})
+
+			It("fails the section when the output matches --fail-on-output", func() {
+				context.FailOnOutput = `^--- FAIL`
+				context.SuccessOnOutput = `ok`
+				writeOutput("ok\n\x1b[31m--- FAIL: TestInstall\x1b[0m\r\n", nil)
+				err := context.Execute([]string{"command"})
+				var sectionError *section.SectionError
+				Expect(errors.As(err, &sectionError)).To(BeTrue())
+				Expect(sectionError.Retval).To(Equal(1))
+				Expect(out).To(Say("Section subcommand exited with 0, output matched --fail-on-output: '--- FAIL: TestInstall'\n"))
+				Expect(out).To(Say(`section-end: 'install' result: 1 exit code: 0`))
+			})
+
+			It("passes the section when the output matches --success-on-output", func() {
+				context.SuccessOnOutput = `all \d+ tests passed`
+				writeOutput("all 12 tests passed", exitError(1))
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(out).To(Say("Section subcommand exited with 1, output matched --success-on-output: 'all 12 tests passed'\n"))
+				Expect(out).To(Say(`section-end: 'install' result: 0 exit code: 1`))
+			})
+
+			It("matches each update of a progress bar as a line", func() {
+				context.SuccessOnOutput = `^downloaded 100%$`
+				writeOutput("downloaded 50%\rdownloaded 100%\rdone", exitError(1))
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(out).To(Say("output matched --success-on-output: 'downloaded 100%'\n"))
+			})
+
+			It("does not keep output without line endings without limit", func() {
+				context.FailOnOutput = `^FAIL`
+				cmd.RunStub = func() error {
+					output := cmd.SetOutputArgsForCall(0)
+					fmt.Fprint(output, strings.Repeat("\x00", 65*1024))
+					fmt.Fprint(output, "FAIL")
+					return nil
+				}
+				Expect(context.Execute([]string{"command"})).NotTo(Succeed())
+				Expect(out).To(Say("output matched --fail-on-output: 'FAIL'\n"))
+			})
+
+			It("does not pass a section that timed out", func() {
+				context.SuccessOnOutput = "."
+				context.Timeout = time.Millisecond
+				signals := make(chan os.Signal, 2)
+				cmd.SignalStub = func(sig os.Signal) error {
+					signals <- sig
+					return nil
+				}
+				cmd.RunStub = func() error {
+					fmt.Fprint(cmd.SetOutputArgsForCall(0), "working\n")
+					<-signals
+					return exitError(143)
+				}
+				Expect(context.Execute([]string{"command"})).NotTo(Succeed())
+				Expect(out).To(Say(`section-end: 'install' result: 124 status: timeout`))
+			})
+
+			It("rejects invalid criteria", func() {
+				context.SuccessCodes = "0,two"
+				Expect(context.Execute([]string{"command"})).To(MatchError("invalid --success-codes 0,two: expected comma separated exit codes"))
+				context.SuccessCodes = ""
+				context.MapExit = []string{"2"}
+				Expect(context.Execute([]string{"command"})).To(MatchError("invalid --map-exit 2: expected FROM=TO exit codes"))
+				context.MapExit = nil
+				context.FailOnOutput = "("
+				Expect(context.Execute([]string{"command"})).To(MatchError(ContainSubstring("invalid --fail-on-output: error parsing regexp")))
+				Expect(cmd.RunCallCount()).To(Equal(0))
+			})
+
+			It("can only be used with the section command", func() {
+				context.Type = "end"
+				context.SuccessCodes = "0,2"
+				Expect(context.Execute([]string{})).To(MatchError("--success-codes, --map-exit, --fail-on-output and --success-on-output can only be used with the section command"))
+			})
+		})
+
+		Context("tracing", func() {
+			var (
+				collector *httptest.Server
+				spans     chan string
+			)
+
+			BeforeEach(func() {
+				spans = make(chan string, 1)
+				collector = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					body, _ := io.ReadAll(r.Body)
+					spans <- string(body)
+				}))
+				context.Tracer = &trace.Tracer{
+					Exporter: &trace.Exporter{Endpoint: collector.URL},
+					Parent:   "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+				}
+				cmd.RunReturns(fmt.Errorf("command failed"))
+			})
+
+			AfterEach(func() {
+				collector.Close()
+			})
+
+			It("exports the section as a span", func() {
+				Expect(context.Execute([]string{"command", "arg"})).NotTo(Succeed())
+
+				var span string
+				Eventually(spans).Should(Receive(&span))
+				Expect(span).To(ContainSubstring(`"traceId":"0af7651916cd43dd8448eb211c80319c"`))
+				Expect(span).To(ContainSubstring(`"parentSpanId":"b7ad6b7169203331"`))
+				Expect(span).To(ContainSubstring(`"name":"install"`))
+				Expect(span).To(ContainSubstring(`{"key":"process.exit_code","value":{"intValue":"-1"}}`))
+				Expect(span).To(ContainSubstring(`"status":{"code":2}`))
+			})
+
+			It("redacts the span", func() {
+				redactor, err := redact.New([]string{"hunter2-password"}, nil, false)
+				Expect(err).NotTo(HaveOccurred())
+				context.Printer.Redactor = redactor
+				context.OnFailure = "login failed for {{.Captured.password}}"
+				context.Capture = []string{`password=(?P<password>\S+)`}
+				cmd.RunStub = func() error {
+					fmt.Fprint(cmd.SetOutputArgsForCall(0), "password=hunter2-password\n")
+					return fmt.Errorf("command failed")
+				}
+
+				Expect(context.Execute([]string{"login", "--password", "hunter2-password"})).NotTo(Succeed())
+
+				var span string
+				Eventually(spans).Should(Receive(&span))
+				Expect(span).To(ContainSubstring(`{"key":"process.command_args","value":{"stringValue":"login --password [REDACTED]"}}`))
+				Expect(span).To(ContainSubstring(`"message":"login failed for [REDACTED]"`))
+				Expect(span).NotTo(ContainSubstring("hunter2-password"))
+			})
+
+			It("passes the span to the subcommand", func() {
+				Expect(context.Execute([]string{"command"})).NotTo(Succeed())
+				Expect(cmd.SetEnvCallCount()).To(Equal(1))
+				Expect(cmd.SetEnvArgsForCall(0)).To(ContainElement(MatchRegexp(`^TRACEPARENT=00-0af7651916cd43dd8448eb211c80319c-[0-9a-f]{16}-01$`)))
+			})
+		})
+
+		Context("redaction", func() {
+			BeforeEach(func() {
+				redactor, err := redact.New([]string{"hunter2-password"}, nil, false)
+				Expect(err).NotTo(HaveOccurred())
+				context.Printer.Redactor = redactor
+				context.OnFailure = "failed to login with hunter2-password"
+				cmd.RunStub = func() error {
+					fmt.Fprint(cmd.SetOutputArgsForCall(0), "logging in with hunter2-password\ndone")
+					return fmt.Errorf("command failed")
+				}
+			})
+
+			It("masks secrets in the subcommand output and records", func() {
+				Expect(context.Execute([]string{"command"})).NotTo(Succeed())
+				Expect(out.Contents()).NotTo(ContainSubstring("hunter2-password"))
+				Expect(out).To(Say("logging in with \\[REDACTED\\]\ndone"))
+				Expect(out).To(Say(`"message":"failed to login with \[REDACTED\]","redactions":2`))
+			})
+
+			It("counts the redactions of each section alone", func() {
+				Expect(context.Execute([]string{"command"})).NotTo(Succeed())
+				Expect(out).To(Say(`"redactions":2`))
+
+				context.Name = "clean"
+				context.Result = 0
+				context.Status = ""
+				cmd.RunStub = nil
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(out).To(Say(`section-end: 'clean' result: 0.* MRL:{"type":"section-end","name":"clean","status":"passed","time":"[^"]+"}\n`))
+				Expect(context.Printer.Redactor.Count()).To(Equal(2))
+			})
+		})
+
+		Context("json format", func() {
+			var subcommandOut *Buffer
+
+			BeforeEach(func() {
+				subcommandOut = NewBuffer()
+				context.Printer.Format = mrlpkg.FormatJSON
+				context.Printer.Err = subcommandOut
+				cmd.RunReturns(fmt.Errorf("command failed"))
+			})
+
+			It("writes only records to the output", func() {
+				Expect(context.Execute([]string{"command"})).NotTo(Succeed())
+				Expect(string(out.Contents())).To(Equal(
+					`{"type":"section-start","name":"install","time":"1973-11-29T10:15:01Z"}` + "\n" +
+						`{"type":"section-end","name":"install","result":-1,"status":"failed","time":"1973-11-29T10:15:01Z"}` + "\n"))
+
+				Expect(cmd.SetOutputArgsForCall(0)).To(Equal(subcommandOut))
+				Expect(subcommandOut).To(Say("Section subcommand failed with -1: command failed"))
+			})
+		})
+
+		Context("debug", func() {
+			var debugOut *Buffer
+
+			BeforeEach(func() {
+				debugOut = NewBuffer()
+				context.Debug = &debug.Logger{Out: debugOut}
+				redactor, err := redact.New([]string{"hunter2-password"}, nil, false)
+				Expect(err).NotTo(HaveOccurred())
+				context.Printer.Redactor = redactor
+				os.Setenv("MRLOG_TEST_PASSWORD", "hunter2-password")
+				os.Setenv("MRLOG_TEST_DB_PASSWORD", "not-a-detected-secret")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("MRLOG_TEST_PASSWORD")
+				os.Unsetenv("MRLOG_TEST_DB_PASSWORD")
+			})
+
+			It("logs the command, the names in its environment and timing separately from the output", func() {
+				context.Env = []string{"TZ=UTC", "API_TOKEN=t0ps3cret"}
+				Expect(context.Execute([]string{"command", "an arg"})).To(Succeed())
+
+				Expect(debugOut).To(Say(`section 'install': exec \["command" "an arg"\]`))
+				Expect(debugOut.Contents()).To(ContainSubstring("section 'install': env MRLOG_TEST_DB_PASSWORD\n"))
+				Expect(debugOut.Contents()).To(ContainSubstring("section 'install': env MRLOG_TEST_PASSWORD\n"))
+				Expect(debugOut.Contents()).To(ContainSubstring("section 'install': env TZ=UTC\n"))
+				Expect(debugOut.Contents()).To(ContainSubstring("section 'install': env API_TOKEN\n"))
+				Expect(debugOut).To(Say(`section 'install' subcommand took`))
+				Expect(debugOut).To(Say(`section 'install': result 0`))
+				Expect(out.Contents()).NotTo(ContainSubstring("mrlog debug"))
+				Expect(debugOut.Contents()).NotTo(ContainSubstring("secret"))
+				Expect(debugOut.Contents()).NotTo(ContainSubstring("hunter2-password"))
+			})
+
+			It("redacts the command and the section name", func() {
+				context.Name = "login as hunter2-password"
+				context.Shell = "curl -u admin:hunter2-password https://example.com"
+				Expect(context.Execute([]string{})).To(Succeed())
+
+				Expect(debugOut).To(Say(`section 'login as \[REDACTED\]': exec \[".*" "-c" "curl -u admin:\[REDACTED\] https://example.com"\]`))
+				Expect(debugOut).To(Say(`section 'login as \[REDACTED\]': result 0`))
+				Expect(debugOut.Contents()).NotTo(ContainSubstring("hunter2-password"))
+				Expect(out).To(Say(`"type":"section-end".*"redactions":2}`))
+			})
+		})
+
+		Context("shell", func() {
+			BeforeEach(func() {
+				context.Shell = "make test | tee test.log"
+				context.ShellOptions = "-e -u"
+			})
+
+			It("runs the script with the shell and records it in the start record", func() {
+				Expect(context.Execute([]string{})).To(Succeed())
+
+				exec := context.Exec.(*execfakes.FakeExec)
+				command, args := exec.CommandArgsForCall(0)
+				Expect(command).To(Equal(section.DefaultShell()))
+				Expect(args).To(Equal([]string{"-e", "-u", "-c", "make test | tee test.log"}))
+				Expect(out).To(Say(`MRL:{"type":"section-start","name":"install","time":"1973-11-29T10:15:01Z","script":"make test \| tee test.log"}`))
+			})
+
+			It("runs the script with the shell given by --shell-path", func() {
+				context.ShellPath = "/usr/bin/zsh"
+				Expect(context.Execute([]string{})).To(Succeed())
+
+				exec := context.Exec.(*execfakes.FakeExec)
+				command, args := exec.CommandArgsForCall(0)
+				Expect(command).To(Equal("/usr/bin/zsh"))
+				Expect(args).To(Equal([]string{"-e", "-u", "-c", "make test | tee test.log"}))
+			})
+
+			It("prefers bash, whatever $SHELL is", func() {
+				os.Setenv("SHELL", "/usr/bin/fish")
+				defer os.Unsetenv("SHELL")
+				Expect(section.DefaultShell()).To(Or(HaveSuffix("/bash"), Equal("/bin/sh")))
+			})
+
+			It("uses pipefail only with bash by default", func() {
+				Expect(section.ShellCommand("/usr/bin/bash", "", "true")).To(Equal([]string{"/usr/bin/bash", "-e", "-o", "pipefail", "-c", "true"}))
+				Expect(section.ShellCommand("/bin/sh", "", "true")).To(Equal([]string{"/bin/sh", "-e", "-c", "true"}))
+				Expect(section.ShellCommand("/bin/sh", "+e", "true")).To(Equal([]string{"/bin/sh", "+e", "-c", "true"}))
+			})
+
+			It("redacts the script", func() {
+				context.Shell = "curl -u admin:hunter2-password https://example.com"
+				redactor, err := redact.New([]string{"hunter2-password"}, nil, false)
+				Expect(err).NotTo(HaveOccurred())
+				context.Printer.Redactor = redactor
+
+				Expect(context.Execute([]string{})).To(Succeed())
+				Expect(out).To(Say(`"script":"curl -u admin:\[REDACTED\] https://example.com"}`))
+				Expect(out).To(Say(`"type":"section-end".*"redactions":1}`))
+			})
+
+			It("does not take a command as well", func() {
+				Expect(context.Execute([]string{"make"})).To(MatchError("the section subcommand takes either --shell or a command, not both"))
+			})
+
+			It("is only allowed for the section command", func() {
+				context.Type = "start"
+				Expect(context.Execute([]string{})).To(MatchError("--shell can only be used with the section command"))
+			})
+		})
+
+		Context("working directory, environment and input", func() {
+			var dir string
+
+			BeforeEach(func() {
+				var err error
+				dir, err = os.MkdirTemp("", "mrlog-section")
+				Expect(err).NotTo(HaveOccurred())
+				os.Setenv("MRLOG_TEST_INHERITED", "inherited")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("MRLOG_TEST_INHERITED")
+				Expect(os.RemoveAll(dir)).To(Succeed())
+			})
+
+			It("runs in mrlog's environment with no input by default", func() {
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(cmd.SetDirCallCount()).To(Equal(0))
+				Expect(cmd.SetStdinArgsForCall(0)).To(BeNil())
+				Expect(cmd.SetEnvArgsForCall(0)).To(ContainElement("MRLOG_TEST_INHERITED=inherited"))
+			})
+
+			It("sets the working directory", func() {
+				context.Cwd = dir
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(cmd.SetDirArgsForCall(0)).To(Equal(dir))
+			})
+
+			It("adds variables from env files and --env, in that order", func() {
+				envFile := filepath.Join(dir, "test.env")
+				Expect(os.WriteFile(envFile, []byte("# comment\n\nexport MRLOG_TEST_INHERITED=from-file\nQUOTED=\"a b\"\nOVERRIDDEN=file\n"), 0644)).To(Succeed())
+				context.EnvFile = []string{envFile}
+				context.Env = []string{"OVERRIDDEN=flag", "EMPTY="}
+
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				env := cmd.SetEnvArgsForCall(0)
+				Expect(env).To(ContainElements("MRLOG_TEST_INHERITED=from-file", "QUOTED=a b", "OVERRIDDEN=flag", "EMPTY="))
+				Expect(env).NotTo(ContainElement("OVERRIDDEN=file"))
+			})
+
+			It("clears the environment", func() {
+				context.ClearEnv = true
+				context.Env = []string{"ONLY=this"}
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(cmd.SetEnvArgsForCall(0)).To(Equal([]string{"ONLY=this"}))
+			})
+
+			It("rejects invalid variables before starting the section", func() {
+				context.Env = []string{"=value"}
+				Expect(context.Execute([]string{"command"})).To(MatchError("invalid --env =value: expected KEY=VALUE"))
+				Expect(out.Contents()).To(BeEmpty())
+			})
+
+			It("rejects invalid env files", func() {
+				envFile := filepath.Join(dir, "test.env")
+				Expect(os.WriteFile(envFile, []byte("FOO=bar\nnot a variable\n"), 0644)).To(Succeed())
+				context.EnvFile = []string{envFile}
+				Expect(context.Execute([]string{"command"})).To(MatchError("invalid env file " + envFile + " line 2: expected KEY=VALUE"))
+			})
+
+			It("rejects missing env files", func() {
+				context.EnvFile = []string{filepath.Join(dir, "missing.env")}
+				Expect(context.Execute([]string{"command"})).To(MatchError(ContainSubstring("failed to open env file")))
+			})
+
+			It("inherits stdin", func() {
+				context.Stdin = section.StdinInherit
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(cmd.SetStdinArgsForCall(0)).To(Equal(os.Stdin))
+			})
+
+			It("reads stdin from a file", func() {
+				input := filepath.Join(dir, "input.txt")
+				Expect(os.WriteFile(input, []byte("yes\n"), 0644)).To(Succeed())
+				context.Stdin = "file:" + input
+				cmd.RunStub = func() error {
+					contents, err := io.ReadAll(cmd.SetStdinArgsForCall(0))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(contents)).To(Equal("yes\n"))
+					return nil
+				}
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+			})
+
+			It("rejects invalid stdin settings", func() {
+				context.Stdin = "pipe"
+				Expect(context.Execute([]string{"command"})).To(MatchError("invalid --stdin pipe: expected inherit, null or file:<path>"))
+
+				context.Stdin = "file:" + filepath.Join(dir, "missing.txt")
+				Expect(context.Execute([]string{"command"})).To(MatchError(ContainSubstring("failed to open stdin")))
+			})
+		})
+
+		Context("resource usage", func() {
+			BeforeEach(func() {
+				color.NoColor = true
+				cmd.UsageReturns(&mrlpkg.Usage{
+					UserCPU:     1.25,
+					SystemCPU:   0.5,
+					MaxRSS:      47185920,
+					BlockInput:  12,
+					BlockOutput: 340,
+				})
+			})
+
+			It("records the subcommand's resource usage in the end record", func() {
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(out).To(Say(`section-end: 'install' result: 0 usage: cpu 1.25s user 0.50s sys, max rss 45.0MiB, blocks 12 in 340 out MRL:`))
+				Expect(out).To(Say(`"usage":{"user_cpu_seconds":1.25,"system_cpu_seconds":0.5,"max_rss_bytes":47185920,"block_input_ops":12,"block_output_ops":340}}`))
+			})
+		})
+
+		Context("pty and output file", func() {
+			var dir string
+
+			BeforeEach(func() {
+				var err error
+				dir, err = os.MkdirTemp("", "mrlog-section")
+				Expect(err).NotTo(HaveOccurred())
+				cmd.RunStub = func() error {
+					fmt.Fprint(cmd.SetOutputArgsForCall(0), "\x1b[32mok\x1b[0m\n")
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(dir)).To(Succeed())
+			})
+
+			It("runs the subcommand with a pty", func() {
+				context.Pty = true
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(cmd.SetPtyArgsForCall(0)).To(BeTrue())
+			})
+
+			It("copies the output to the output file", func() {
+				context.OutputFile = filepath.Join(dir, "output.log")
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+
+				Expect(out).To(Say("\x1b\\[32mok\x1b\\[0m\n"))
+				Expect(os.ReadFile(context.OutputFile)).To(Equal([]byte("\x1b[32mok\x1b[0m\n")))
+			})
+
+			It("strips ANSI escapes from the output file copy only", func() {
+				context.OutputFile = filepath.Join(dir, "output.log")
+				context.StripANSI = true
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+
+				Expect(out).To(Say("\x1b\\[32mok\x1b\\[0m\n"))
+				Expect(os.ReadFile(context.OutputFile)).To(Equal([]byte("ok\n")))
+			})
+
+			It("requires an output file to strip ANSI escapes", func() {
+				context.StripANSI = true
+				Expect(context.Execute([]string{"command"})).To(MatchError("--strip-ansi requires --output-file"))
+			})
+
+			It("fails before starting the section if the output file cannot be created", func() {
+				context.OutputFile = filepath.Join(dir, "missing", "output.log")
+				Expect(context.Execute([]string{"command"})).To(MatchError(ContainSubstring("failed to create output file")))
+				Expect(out.Contents()).To(BeEmpty())
+			})
+		})
+
+		Context("timestamps", func() {
+			var clock *clockfakes.FakeClock
+
+			BeforeEach(func() {
+				clock = context.Clock.(*clockfakes.FakeClock)
+				start := time.Date(1973, 11, 29, 10, 15, 01, 00, time.UTC)
+				calls := 0
+				clock.NowStub = func() time.Time {
+					calls++
+					return start.Add(time.Duration(calls-1) * 1500 * time.Millisecond)
+				}
+				cmd.RunStub = func() error {
+					output := cmd.SetOutputArgsForCall(0)
+					for _, chunk := range []string{"first line\nsecond ", "line\n", "10%\r", "\n50%\r100%\r\n", "partial"} {
+						fmt.Fprint(output, chunk)
+					}
+					return nil
+				}
+			})
+
+			It("prefixes lines with the time", func() {
+				context.Timestamps = section.TimestampsAbsolute
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(out).To(Say(`\[1973-11-29T10:15:04.000Z\] first line\n` +
+					`\[1973-11-29T10:15:05.500Z\] second line\n` +
+					`\[1973-11-29T10:15:07.000Z\] 10%\r\n` +
+					`\[1973-11-29T10:15:08.500Z\] 50%\r` +
+					`\[1973-11-29T10:15:10.000Z\] 100%\r\n` +
+					`\[1973-11-29T10:15:11.500Z\] partial`))
+			})
+
+			It("prefixes lines with the time since the section started", func() {
+				context.Timestamps = section.TimestampsRelative
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(out).To(Say(`\[\+3.000s\] first line\n` +
+					`\[\+4.500s\] second line\n` +
+					`\[\+6.000s\] 10%\r\n` +
+					`\[\+7.500s\] 50%\r` +
+					`\[\+9.000s\] 100%\r\n` +
+					`\[\+10.500s\] partial`))
+			})
+
+			It("still matches the output against --fail-on-output", func() {
+				context.Timestamps = section.TimestampsRelative
+				context.FailOnOutput = `^second line$`
+				err := context.Execute([]string{"command"})
+				var sectionError *section.SectionError
+				Expect(errors.As(err, &sectionError)).To(BeTrue())
+				Expect(sectionError.Retval).To(Equal(1))
+				Expect(out).To(Say(`\[\+4.500s\] second line\n`))
+				Expect(out).To(Say("Section subcommand exited with 0, output matched --fail-on-output: 'second line'\n"))
+			})
+
+			It("leaves the output alone by default", func() {
+				context.Timestamps = section.TimestampsNone
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(out).To(Say("first line\nsecond line\n10%\r\n50%\r100%\r\npartial"))
+			})
+		})
+
+		Context("timeout", func() {
+			var signals chan os.Signal
+
+			BeforeEach(func() {
+				signals = make(chan os.Signal, 2)
+				context.Timeout = 10 * time.Millisecond
+				context.OnFailure = "took too long"
+				cmd.SignalStub = func(sig os.Signal) error {
+					signals <- sig
+					return nil
+				}
+			})
+
+			It("terminates the subcommand and fails the section", func() {
+				cmd.RunStub = func() error {
+					<-signals
+					return errors.New("signal: terminated")
+				}
+
+				err := context.Execute([]string{"command"})
+				var sectionError *section.SectionError
+				Expect(errors.As(err, &sectionError)).To(BeTrue())
+				Expect(sectionError.Retval).To(Equal(section.TimeoutExitCode))
+
+				Expect(cmd.SetProcessGroupArgsForCall(0)).To(BeTrue())
+				Expect(cmd.SignalArgsForCall(0)).To(Equal(syscall.SIGTERM))
+				Expect(out).To(Say("Section subcommand timed out after 10ms"))
+				Expect(out).To(Say("section-end: 'install' result: 124 status: timeout message: 'took too long'"))
+			})
+
+			It("kills the subcommand if it ignores SIGTERM", func() {
+				defer func(period time.Duration) { section.TerminateGracePeriod = period }(section.TerminateGracePeriod)
+				section.TerminateGracePeriod = 10 * time.Millisecond
+				cmd.RunStub = func() error {
+					<-signals
+					<-signals
+					return errors.New("signal: killed")
+				}
+
+				Expect(context.Execute([]string{"command"})).NotTo(Succeed())
+				Expect(cmd.SignalCallCount()).To(Equal(2))
+				Expect(cmd.SignalArgsForCall(1)).To(Equal(syscall.SIGKILL))
+			})
+
+			It("does not signal a subcommand that finishes in time", func() {
+				context.Timeout = time.Minute
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(cmd.SignalCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("heartbeat", func() {
+			var signals chan os.Signal
+
+			BeforeEach(func() {
+				signals = make(chan os.Signal, 2)
+				cmd.PidReturns(4242)
+				cmd.SignalStub = func(sig os.Signal) error {
+					signals <- sig
+					return nil
+				}
+
+				started := time.Now()
+				context.Clock.(*clockfakes.FakeClock).NowStub = func() time.Time {
+					return time.Date(1973, 11, 29, 10, 15, 01, 00, time.UTC).Add(time.Since(started))
+				}
+				section.MinimumSilence = time.Millisecond
+			})
+
+			AfterEach(func() {
+				section.MinimumSilence = time.Second
+			})
+
+			It("logs a heartbeat while the subcommand is silent", func() {
+				context.Heartbeat = 20 * time.Millisecond
+				cmd.RunStub = func() error {
+					fmt.Fprint(cmd.SetOutputArgsForCall(0), "downloading...")
+					time.Sleep(100 * time.Millisecond)
+					return nil
+				}
+
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(out).To(Say("downloading...\n"))
+				Expect(out).To(Say(`section-heartbeat: 'install' running for 0s, silent for 0s, 14 bytes of output MRL:{"type":"section-heartbeat","name":"install","time":"1973-11-29T10:15:01.[0-9]+Z","elapsed_seconds":[0-9.e-]+,"pid":4242,"output_bytes":14}\n`))
+				Expect(out).To(Say("section-heartbeat: 'install'"))
+				Expect(out).To(Say("section-end: 'install' result: 0"))
+				Expect(cmd.SignalCallCount()).To(Equal(0))
+			})
+
+			It("does not log a heartbeat while the subcommand writes output", func() {
+				context.Heartbeat = time.Minute
+				Expect(context.Execute([]string{"command"})).To(Succeed())
+				Expect(out).NotTo(Say("section-heartbeat"))
+				// without a stall timeout, the subcommand is never stopped
+				Expect(cmd.SetProcessGroupCallCount()).To(Equal(0))
+			})
+
+			It("terminates a stalled subcommand and fails the section", func() {
+				context.StallTimeout = 20 * time.Millisecond
+				context.OnFailure = "stalled"
+				cmd.RunStub = func() error {
+					<-signals
+					return errors.New("signal: terminated")
+				}
+
+				err := context.Execute([]string{"command"})
+				var sectionError *section.SectionError
+				Expect(errors.As(err, &sectionError)).To(BeTrue())
+				Expect(sectionError.Retval).To(Equal(section.TimeoutExitCode))
+
+				Expect(cmd.SetProcessGroupArgsForCall(0)).To(BeTrue())
+				Expect(cmd.SignalArgsForCall(0)).To(Equal(syscall.SIGTERM))
+				Expect(out).To(Say("Section subcommand stalled: no output for 20ms"))
+				Expect(out).To(Say("section-end: 'install' result: 124 status: timeout message: 'stalled'"))
+			})
+
+			It("rejects intervals below the minimum before starting the section", func() {
+				section.MinimumSilence = time.Second
+				context.Heartbeat = -time.Second
+				Expect(context.Execute([]string{"command"})).To(MatchError("invalid --heartbeat -1s: expected at least 1s"))
+				context.Heartbeat = 0
+				context.StallTimeout = time.Nanosecond
+				Expect(context.Execute([]string{"command"})).To(MatchError("invalid --stall-timeout 1ns: expected at least 1s"))
+				Expect(out.Contents()).To(BeEmpty())
+			})
+		})
+
 		Context("no color flag", func() {
 			BeforeEach(func() {
 				context.Type = "section"
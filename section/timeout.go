@@ -0,0 +1,62 @@
+package section
+
+import (
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/exec"
+)
+
+// TimeoutExitCode is the result of a section whose subcommand timed out or
+// stalled, matching the GNU timeout command.
+const TimeoutExitCode = 124
+
+// TerminateGracePeriod is how long a timed out subcommand has to exit after
+// SIGTERM before it is sent SIGKILL.
+var TerminateGracePeriod = 10 * time.Second
+
+type deadline struct {
+	mutex   sync.Mutex
+	cmd     exec.Cmd
+	timers  []*time.Timer
+	reason  string
+	stopped bool
+}
+
+// startDeadline terminates cmd once timeout has elapsed, if it is set.
+func startDeadline(cmd exec.Cmd, timeout time.Duration) *deadline {
+	d := &deadline{cmd: cmd}
+	if timeout > 0 {
+		d.timers = append(d.timers, time.AfterFunc(timeout, func() {
+			d.Expire("timed out after " + timeout.String())
+		}))
+	}
+	return d
+}
+
+// Expire terminates the command now: it is sent SIGTERM and, if it is still
+// running after the grace period, SIGKILL.
+func (d *deadline) Expire(reason string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.stopped || d.reason != "" {
+		return
+	}
+	d.reason = reason
+	d.cmd.Signal(syscall.SIGTERM)
+	d.timers = append(d.timers, time.AfterFunc(TerminateGracePeriod, func() {
+		d.cmd.Signal(syscall.SIGKILL)
+	}))
+}
+
+// Stop cancels the deadline and returns why it expired, if it did.
+func (d *deadline) Stop() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.stopped = true
+	for _, timer := range d.timers {
+		timer.Stop()
+	}
+	return d.reason
+}
@@ -0,0 +1,78 @@
+package section
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/clock"
+)
+
+const (
+	TimestampsAbsolute = "absolute"
+	TimestampsRelative = "relative"
+	TimestampsNone     = "none"
+)
+
+// timestampWriter prefixes every line written through it with a timestamp,
+// taken when the first byte of the line arrives. Carriage returns start a
+// new line too, so every redraw of a progress bar is timed.
+type timestampWriter struct {
+	out       io.Writer
+	prefix    func() string
+	lineStart bool
+	afterCR   bool
+}
+
+func newTimestampWriter(out io.Writer, mode string, clock clock.Clock, start time.Time) *timestampWriter {
+	prefix := func() string {
+		return fmt.Sprintf("[%s] ", clock.Now().Format("2006-01-02T15:04:05.000Z07:00"))
+	}
+	if mode == TimestampsRelative {
+		prefix = func() string {
+			return fmt.Sprintf("[+%.3fs] ", clock.Now().Sub(start).Seconds())
+		}
+	}
+	return &timestampWriter{out: out, prefix: prefix, lineStart: true}
+}
+
+func (w *timestampWriter) Write(p []byte) (int, error) {
+	var prefixed bytes.Buffer
+	rest := p
+	for len(rest) > 0 {
+		// the \n of a \r\n line ending split across writes
+		if w.afterCR && rest[0] == '\n' {
+			prefixed.WriteByte('\n')
+			rest = rest[1:]
+			w.afterCR = false
+			continue
+		}
+		if w.lineStart {
+			prefixed.WriteString(w.prefix())
+			w.lineStart = false
+		}
+		w.afterCR = false
+
+		end := bytes.IndexAny(rest, "\r\n")
+		if end < 0 {
+			prefixed.Write(rest)
+			break
+		}
+		if rest[end] == '\r' {
+			if end+1 < len(rest) && rest[end+1] == '\n' {
+				end++
+			} else if end+1 == len(rest) {
+				w.afterCR = true
+			}
+		}
+		prefixed.Write(rest[:end+1])
+		rest = rest[end+1:]
+		w.lineStart = true
+	}
+
+	if _, err := w.out.Write(prefixed.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,261 @@
+package signing
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+const SignatureExtension = ".sig"
+
+// Records returns the canonical form of every MRL record in a log, ignoring
+// human readable text and other output.
+func Records(in io.Reader) ([][]byte, error) {
+	var records [][]byte
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		_, ok, err := mrl.Parse(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("invalid MRL record: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		record, _ := mrl.Extract(scanner.Text())
+		canonical, err := mrl.Canonical([]byte(record))
+		if err != nil { // !branch-not-tested
+			return nil, err
+		}
+		records = append(records, canonical)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read log: %w", err)
+	}
+	return records, nil
+}
+
+// message is what a detached signature covers: the canonical records, one
+// per line.
+func message(records [][]byte) []byte {
+	return append(bytes.Join(records, []byte("\n")), '\n')
+}
+
+func readRecords(path string) ([][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log: %w", err)
+	}
+	defer file.Close()
+	return Records(file)
+}
+
+// SignRecord returns the per-record signature of a JSON record: the base64
+// encoded signature of its canonical form without the sig field.
+func (key *Key) SignRecord(record []byte) (string, error) {
+	canonical, err := mrl.Canonical(record, "sig")
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key.Sign(canonical)), nil
+}
+
+// VerifyRecord checks the sig field of a canonical JSON record.
+func VerifyRecord(key ed25519.PublicKey, record []byte) (bool, error) {
+	signed := &struct {
+		Sig string `json:"sig"`
+	}{}
+	if err := json.Unmarshal(record, signed); err != nil {
+		return false, err
+	}
+	if signed.Sig == "" {
+		return false, nil
+	}
+	signature, err := base64.StdEncoding.DecodeString(signed.Sig)
+	if err != nil {
+		return false, fmt.Errorf("invalid record signature: %w", err)
+	}
+	canonical, err := mrl.Canonical(record, "sig")
+	if err != nil { // !branch-not-tested
+		return false, err
+	}
+	if !ed25519.Verify(key, canonical, signature) {
+		return false, errors.New("invalid record signature")
+	}
+	return true, nil
+}
+
+type KeygenOpt struct {
+	Prefix string `long:"out" description:"path prefix for the generated key files" default:"mrlog"`
+	Force  bool   `long:"force" description:"overwrite existing key files"`
+	Out    io.Writer
+}
+
+// writeKey writes a key file, only replacing an existing one when forced.
+func writeKey(path string, contents []byte, perm os.FileMode, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(path, flags, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(contents); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (opts *KeygenOpt) Execute(args []string) error {
+	public, private, err := GenerateKey()
+	if err != nil { // !branch-not-tested
+		return err
+	}
+
+	privatePEM, err := EncodePrivateKey(private)
+	if err != nil { // !branch-not-tested
+		return err
+	}
+	publicPEM, err := EncodePublicKey(public)
+	if err != nil { // !branch-not-tested
+		return err
+	}
+
+	privatePath := opts.Prefix + ".pem"
+	publicPath := opts.Prefix + ".pub"
+	if !opts.Force {
+		for _, path := range []string{privatePath, publicPath} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists, use --force to replace it", path)
+			}
+		}
+	}
+	if err := writeKey(privatePath, privatePEM, 0600, opts.Force); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := writeKey(publicPath, publicPEM, 0644, opts.Force); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+
+	_, err = fmt.Fprintf(opts.Out, "wrote private key %s and public key %s\n", privatePath, publicPath)
+	return err
+}
+
+type SignOpt struct {
+	Key       string `long:"key" description:"Ed25519 private key in PEM format" required:"true"`
+	Signature string `long:"signature" description:"where to write the signature, defaults to the log file with a .sig extension"`
+	Out       io.Writer
+}
+
+func (opts *SignOpt) Execute(args []string) error {
+	if len(args) != 1 {
+		return errors.New("sign requires a log file argument")
+	}
+
+	key, err := LoadPrivateKey(opts.Key)
+	if err != nil {
+		return err
+	}
+	records, err := readRecords(args[0])
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return errors.New("no MRL records found")
+	}
+
+	signaturePath := opts.Signature
+	if signaturePath == "" {
+		signaturePath = args[0] + SignatureExtension
+	}
+	signature := base64.StdEncoding.EncodeToString(key.Sign(message(records))) + "\n"
+	if err := os.WriteFile(signaturePath, []byte(signature), 0644); err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+
+	_, err = fmt.Fprintf(opts.Out, "signed %d records: %s\n", len(records), signaturePath)
+	return err
+}
+
+type VerifyOpt struct {
+	Pub       string `long:"pub" description:"Ed25519 public key in PEM format" required:"true"`
+	Signature string `long:"signature" description:"detached signature, defaults to the log file with a .sig extension"`
+	Out       io.Writer
+}
+
+// Execute verifies the detached signature of a log, if there is one, and the
+// record signatures, if there are any. At least one of the two is needed, and
+// once records are signed every record has to be, so that records cannot be
+// added without a signature.
+func (opts *VerifyOpt) Execute(args []string) error {
+	if len(args) != 1 {
+		return errors.New("verify requires a log file argument")
+	}
+
+	key, err := LoadPublicKey(opts.Pub)
+	if err != nil {
+		return err
+	}
+	records, err := readRecords(args[0])
+	if err != nil {
+		return err
+	}
+
+	signaturePath := opts.Signature
+	if signaturePath == "" {
+		signaturePath = args[0] + SignatureExtension
+	}
+	var verified []string
+
+	encoded, err := os.ReadFile(signaturePath)
+	if err == nil {
+		signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+		if err != nil {
+			return fmt.Errorf("invalid signature: %w", err)
+		}
+		if !ed25519.Verify(key, message(records), signature) {
+			return errors.New("log signature verification failed: the records were modified or signed with another key")
+		}
+		verified = append(verified, "log signature")
+	} else if opts.Signature != "" || !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+
+	signedRecords := 0
+	unsigned := 0
+	for i, record := range records {
+		signed, err := VerifyRecord(key, record)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", i+1, err)
+		}
+		if signed {
+			signedRecords++
+		} else if unsigned == 0 {
+			unsigned = i + 1
+		}
+	}
+	if signedRecords > 0 && unsigned > 0 {
+		return fmt.Errorf("record %d: missing record signature", unsigned)
+	}
+	if signedRecords > 0 {
+		verified = append(verified, fmt.Sprintf("%d record signatures", signedRecords))
+	}
+
+	if len(verified) == 0 {
+		return errors.New("nothing to verify: no signature file and no signed records")
+	}
+
+	_, err = fmt.Fprintf(opts.Out, "verified %s for %d records\n", strings.Join(verified, " and "), len(records))
+	return err
+}
@@ -0,0 +1,159 @@
+package signing_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/signing"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Signing", func() {
+	var (
+		dir     string
+		prefix  string
+		logPath string
+		out     *Buffer
+	)
+
+	writeLog := func(contents string) {
+		Expect(os.WriteFile(logPath, []byte(contents), 0644)).To(Succeed())
+	}
+
+	sign := func() error {
+		return (&signing.SignOpt{Key: prefix + ".pem", Out: out}).Execute([]string{logPath})
+	}
+
+	verify := func() error {
+		return (&signing.VerifyOpt{Pub: prefix + ".pub", Out: out}).Execute([]string{logPath})
+	}
+
+	const log = `section-start: 'install' MRL:{"type":"section-start","name":"install","time":"1973-11-29T10:15:01Z"}
+installing
+section-end: 'install' result: 0 MRL:{"type":"section-end","name":"install","time":"1973-11-29T10:15:01Z"}
+`
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "mrlog-signing")
+		Expect(err).NotTo(HaveOccurred())
+		prefix = filepath.Join(dir, "key")
+		logPath = filepath.Join(dir, "build.log")
+		out = NewBuffer()
+
+		Expect((&signing.KeygenOpt{Prefix: prefix, Out: out}).Execute([]string{})).To(Succeed())
+		Expect(out).To(Say("wrote private key .*key.pem and public key .*key.pub"))
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("generates a key pair", func() {
+		info, err := os.Stat(prefix + ".pem")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+		_, err = signing.LoadPrivateKey(prefix + ".pem")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = signing.LoadPublicKey(prefix + ".pub")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("does not replace existing keys unless forced", func() {
+		private, err := os.ReadFile(prefix + ".pem")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = (&signing.KeygenOpt{Prefix: prefix, Out: out}).Execute([]string{})
+		Expect(err).To(MatchError(HaveSuffix("key.pem already exists, use --force to replace it")))
+		Expect(os.ReadFile(prefix + ".pem")).To(Equal(private))
+
+		Expect((&signing.KeygenOpt{Prefix: prefix, Force: true, Out: out}).Execute([]string{})).To(Succeed())
+		Expect(os.ReadFile(prefix + ".pem")).NotTo(Equal(private))
+	})
+
+	It("rejects keys of the wrong kind", func() {
+		_, err := signing.LoadPrivateKey(prefix + ".pub")
+		Expect(err).To(MatchError(ContainSubstring("does not contain a PEM encoded PRIVATE KEY")))
+	})
+
+	Context("detached signatures", func() {
+		BeforeEach(func() {
+			writeLog(log)
+			Expect(sign()).To(Succeed())
+			Expect(out).To(Say("signed 2 records: .*build.log.sig"))
+		})
+
+		It("verifies the signed log", func() {
+			Expect(verify()).To(Succeed())
+			Expect(out).To(Say("verified log signature for 2 records"))
+		})
+
+		It("ignores changes to human readable text", func() {
+			writeLog(strings.Replace(log, "installing", "installing, again", 1))
+			Expect(verify()).To(Succeed())
+		})
+
+		It("detects changes to records", func() {
+			writeLog(strings.Replace(log, `"name":"install","time"`, `"name":"uninstall","time"`, 1))
+			Expect(verify()).To(MatchError(ContainSubstring("log signature verification failed")))
+		})
+
+		It("detects removed records", func() {
+			writeLog(strings.SplitAfterN(log, "\n", 2)[1])
+			Expect(verify()).To(MatchError(ContainSubstring("log signature verification failed")))
+		})
+
+		It("detects another key", func() {
+			Expect((&signing.KeygenOpt{Prefix: prefix, Force: true, Out: out}).Execute([]string{})).To(Succeed())
+			Expect(verify()).To(MatchError(ContainSubstring("log signature verification failed")))
+		})
+	})
+
+	Context("record signatures", func() {
+		var printed *Buffer
+
+		BeforeEach(func() {
+			key, err := signing.LoadPrivateKey(prefix + ".pem")
+			Expect(err).NotTo(HaveOccurred())
+
+			printed = NewBuffer()
+			printer := &mrl.Printer{Out: printed, Signer: key}
+			Expect(printer.Print("section-start: 'install'", &mrl.MachineReadableLog{
+				Type: "section-start",
+				Name: "install",
+				Time: time.Date(1973, 11, 29, 10, 15, 01, 00, time.UTC),
+			}, "\n")).To(Succeed())
+		})
+
+		It("adds a sig field to each record", func() {
+			Expect(string(printed.Contents())).To(MatchRegexp(`"sig":"[A-Za-z0-9+/]{86}=="}\n$`))
+		})
+
+		It("verifies the record signatures", func() {
+			writeLog(string(printed.Contents()))
+			Expect(verify()).To(Succeed())
+			Expect(out).To(Say("verified 1 record signatures for 1 records"))
+		})
+
+		It("detects changed records", func() {
+			writeLog(strings.Replace(string(printed.Contents()), `"name":"install"`, `"name":"other"`, 1))
+			Expect(verify()).To(MatchError("record 1: invalid record signature"))
+		})
+
+		It("detects unsigned records among signed records", func() {
+			writeLog(string(printed.Contents()) + `section-end: 'install' result: 0 MRL:{"type":"section-end","name":"install","time":"1973-11-29T10:15:01Z"}` + "\n")
+			Expect(verify()).To(MatchError("record 2: missing record signature"))
+		})
+	})
+
+	It("requires something to verify", func() {
+		writeLog(log)
+		Expect(verify()).To(MatchError("nothing to verify: no signature file and no signed records"))
+	})
+})
@@ -0,0 +1,35 @@
+package sink
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// File appends records to a file as JSON lines. The file is opened in append
+// mode and held under an exclusive advisory lock while each line is written,
+// so that concurrent mrlog processes never interleave partial lines.
+type File struct {
+	Path string
+}
+
+func (f *File) Write(record []byte) error {
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open MRL file: %w", err)
+	}
+	defer file.Close()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock MRL file: %w", err)
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	line := make([]byte, 0, len(record)+1)
+	line = append(line, record...)
+	line = append(line, '\n')
+	if _, err := file.Write(line); err != nil {
+		return fmt.Errorf("failed to write MRL file: %w", err)
+	}
+	return nil
+}
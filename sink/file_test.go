@@ -0,0 +1,72 @@
+package sink_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/cf-platform-eng/mrlog/sink"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("File", func() {
+	var (
+		dir  string
+		path string
+		file *sink.File
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "mrlog-file-sink")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "mrl.log")
+		file = &sink.File{Path: path}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("appends records as lines", func() {
+		Expect(os.WriteFile(path, []byte("{\"type\":\"existing\"}\n"), 0644)).To(Succeed())
+		Expect(file.Write([]byte(`{"type":"section-start"}`))).To(Succeed())
+
+		contents, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("{\"type\":\"existing\"}\n{\"type\":\"section-start\"}\n"))
+	})
+
+	It("never interleaves concurrent writes", func() {
+		record := fmt.Sprintf(`{"type":"section-start","name":"%s"}`, strings.Repeat("x", 8192))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect((&sink.File{Path: path}).Write([]byte(record))).To(Succeed())
+			}()
+		}
+		wg.Wait()
+
+		contents, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		lines := strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+		Expect(lines).To(HaveLen(20))
+		for _, line := range lines {
+			Expect(line).To(Equal(record))
+		}
+	})
+
+	It("returns an error when the file cannot be opened", func() {
+		file.Path = filepath.Join(dir, "missing", "mrl.log")
+		err := file.Write([]byte(`{}`))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed to open MRL file"))
+	})
+})
@@ -0,0 +1,245 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/debug"
+)
+
+const (
+	DefaultBatchSize = 100
+	DefaultRetries   = 3
+	DefaultBackoff   = 200 * time.Millisecond
+	DefaultSpoolSize = 1024 * 1024
+)
+
+// Forwarder batches records and delivers them through a Transport. Failed
+// deliveries are retried with exponential backoff and then kept in a bounded
+// spool file, to be delivered ahead of newer records on the next flush.
+// Delivery problems are only reported as warnings, so a broken collector
+// never fails the command that is logging. Full batches are delivered in the
+// background, so that a slow collector does not hold up the records being
+// written; Close waits for them. Forwarders are safe for concurrent use.
+type Forwarder struct {
+	Name      string
+	Transport Transport
+	SpoolDir  string
+	SpoolSize int64
+	BatchSize int
+	Retries   int
+	Backoff   time.Duration
+	Warn      io.Writer
+	Debug     *debug.Logger
+
+	mutex   sync.Mutex
+	pending [][]byte
+	full    chan struct{}
+	sent    sync.WaitGroup
+}
+
+func NewForwarder(sinkURL string, spoolDir string, warn io.Writer) (*Forwarder, error) {
+	transport, err := NewTransport(sinkURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Forwarder{
+		Name:      sinkURL,
+		Transport: transport,
+		SpoolDir:  spoolDir,
+		SpoolSize: DefaultSpoolSize,
+		BatchSize: DefaultBatchSize,
+		Retries:   DefaultRetries,
+		Backoff:   DefaultBackoff,
+		Warn:      warn,
+	}, nil
+}
+
+// DefaultSpoolDir is the spool location used when none is configured.
+func DefaultSpoolDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil { // !branch-not-tested
+		cacheDir = os.TempDir()
+	}
+	return filepath.Join(cacheDir, "mrlog", "spool")
+}
+
+func (f *Forwarder) Write(record []byte) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.pending = append(f.pending, append([]byte{}, record...))
+	if len(f.pending) < f.BatchSize {
+		return nil
+	}
+	if f.full == nil {
+		f.full = make(chan struct{}, 1)
+		f.sent.Add(1)
+		go f.deliver(f.full)
+	}
+	select {
+	case f.full <- struct{}{}:
+	default:
+		// a delivery is already due and takes these records along
+	}
+	return nil
+}
+
+// deliver flushes the pending records each time a batch is full, until full
+// is closed.
+func (f *Forwarder) deliver(full <-chan struct{}) {
+	defer f.sent.Done()
+	for range full {
+		f.mutex.Lock()
+		records := f.pending
+		f.pending = nil
+		f.mutex.Unlock()
+		if len(records) > 0 {
+			f.flush(records)
+		}
+	}
+}
+
+func (f *Forwarder) Close() error {
+	f.mutex.Lock()
+	if f.full != nil {
+		close(f.full)
+		f.full = nil
+	}
+	f.mutex.Unlock()
+	f.sent.Wait()
+
+	f.mutex.Lock()
+	records := f.pending
+	f.pending = nil
+	f.mutex.Unlock()
+	f.flush(records)
+	return nil
+}
+
+func (f *Forwarder) warn(format string, args ...interface{}) {
+	if f.Warn != nil {
+		fmt.Fprintf(f.Warn, "warning: sink %s: %s\n", f.Name, fmt.Sprintf(format, args...))
+	}
+}
+
+func (f *Forwarder) send(records [][]byte) error {
+	backoff := f.Backoff
+	var err error
+	for attempt := 0; attempt <= f.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		f.Debug.Printf("sink %s: sending %d records, attempt %d", f.Name, len(records), attempt+1)
+		if err = f.Transport.Send(records); err == nil {
+			f.Debug.Printf("sink %s: delivered %d records", f.Name, len(records))
+			return nil
+		}
+		f.Debug.Printf("sink %s: attempt %d failed: %s", f.Name, attempt+1, err)
+	}
+	return err
+}
+
+func (f *Forwarder) spoolPath() string {
+	return filepath.Join(f.SpoolDir, fmt.Sprintf("%x.jsonl", sha256.Sum256([]byte(f.Name))))
+}
+
+// flush delivers spooled records and then the given records. The spool is
+// locked for the duration so that concurrent mrlog processes do not deliver
+// records twice.
+func (f *Forwarder) flush(records [][]byte) {
+	if f.SpoolDir == "" {
+		if len(records) == 0 {
+			return
+		}
+		if err := f.send(records); err != nil {
+			f.warn("dropped %d records: %s", len(records), err)
+		}
+		return
+	}
+
+	if err := os.MkdirAll(f.SpoolDir, 0700); err != nil {
+		f.warn("failed to create spool: %s", err)
+		return
+	}
+	spool, err := os.OpenFile(f.spoolPath(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		f.warn("failed to open spool: %s", err)
+		return
+	}
+	defer spool.Close()
+
+	if err := syscall.Flock(int(spool.Fd()), syscall.LOCK_EX); err != nil { // !branch-not-tested
+		f.warn("failed to lock spool: %s", err)
+		return
+	}
+	defer syscall.Flock(int(spool.Fd()), syscall.LOCK_UN)
+
+	var spooled [][]byte
+	scanner := bufio.NewScanner(spool)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			spooled = append(spooled, append([]byte{}, scanner.Bytes()...))
+		}
+	}
+	f.Debug.Printf("sink %s: read %d spooled records from %s", f.Name, len(spooled), f.spoolPath())
+	records = append(spooled, records...)
+	if len(records) == 0 {
+		return
+	}
+
+	err = f.send(records)
+	if err == nil {
+		if len(spooled) > 0 {
+			if err := spool.Truncate(0); err != nil { // !branch-not-tested
+				f.warn("failed to clear spool: %s", err)
+			}
+		}
+		return
+	}
+
+	kept := f.bound(records)
+	f.warn("failed to deliver %d records, spooled %d: %s", len(records), len(kept), err)
+	if err := rewrite(spool, kept); err != nil { // !branch-not-tested
+		f.warn("failed to write spool: %s", err)
+		return
+	}
+	f.Debug.Printf("sink %s: wrote %d records to %s", f.Name, len(kept), f.spoolPath())
+}
+
+// bound drops the oldest records until the spool fits within SpoolSize.
+func (f *Forwarder) bound(records [][]byte) [][]byte {
+	var size int64
+	for i := len(records) - 1; i >= 0; i-- {
+		size += int64(len(records[i]) + 1)
+		if size > f.SpoolSize {
+			return records[i+1:]
+		}
+	}
+	return records
+}
+
+func rewrite(spool *os.File, records [][]byte) error {
+	if err := spool.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	body := bytes.Join(records, []byte("\n"))
+	body = append(body, '\n')
+	_, err := spool.Write(body)
+	return err
+}
@@ -0,0 +1,148 @@
+package sink_test
+
+import (
+	"errors"
+	"os"
+
+	"github.com/cf-platform-eng/mrlog/debug"
+	"github.com/cf-platform-eng/mrlog/sink"
+	"github.com/cf-platform-eng/mrlog/sink/sinkfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Forwarder", func() {
+	var (
+		dir       string
+		warn      *Buffer
+		transport *sinkfakes.FakeTransport
+		forwarder *sink.Forwarder
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "mrlog-spool")
+		Expect(err).NotTo(HaveOccurred())
+
+		warn = NewBuffer()
+		transport = &sinkfakes.FakeTransport{}
+		forwarder = &sink.Forwarder{
+			Name:      "http://localhost:8080/ingest",
+			Transport: transport,
+			SpoolDir:  dir,
+			SpoolSize: 1024,
+			BatchSize: 3,
+			Retries:   2,
+			Warn:      warn,
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("batches records until closed", func() {
+		Expect(forwarder.Write([]byte(`{"type":"a"}`))).To(Succeed())
+		Expect(forwarder.Write([]byte(`{"type":"b"}`))).To(Succeed())
+		Expect(transport.SendCallCount()).To(Equal(0))
+
+		Expect(forwarder.Close()).To(Succeed())
+		Expect(transport.SendCallCount()).To(Equal(1))
+		Expect(transport.SendArgsForCall(0)).To(Equal([][]byte{[]byte(`{"type":"a"}`), []byte(`{"type":"b"}`)}))
+	})
+
+	It("sends full batches immediately", func() {
+		for _, record := range []string{`{"type":"a"}`, `{"type":"b"}`, `{"type":"c"}`} {
+			Expect(forwarder.Write([]byte(record))).To(Succeed())
+		}
+		Eventually(transport.SendCallCount).Should(Equal(1))
+		Expect(transport.SendArgsForCall(0)).To(HaveLen(3))
+		Expect(forwarder.Close()).To(Succeed())
+	})
+
+	It("keeps writing while a batch is being delivered", func() {
+		delivering := make(chan struct{})
+		release := make(chan struct{})
+		transport.SendStub = func(records [][]byte) error {
+			if transport.SendCallCount() == 1 {
+				close(delivering)
+				<-release
+			}
+			return nil
+		}
+		for _, record := range []string{`{"type":"a"}`, `{"type":"b"}`, `{"type":"c"}`} {
+			Expect(forwarder.Write([]byte(record))).To(Succeed())
+		}
+		Eventually(delivering).Should(BeClosed())
+
+		for _, record := range []string{`{"type":"d"}`, `{"type":"e"}`, `{"type":"f"}`, `{"type":"g"}`, `{"type":"h"}`, `{"type":"i"}`, `{"type":"j"}`} {
+			Expect(forwarder.Write([]byte(record))).To(Succeed())
+		}
+		Expect(transport.SendCallCount()).To(Equal(1))
+
+		close(release)
+		Expect(forwarder.Close()).To(Succeed())
+		Expect(transport.SendCallCount()).To(Equal(2))
+		Expect(transport.SendArgsForCall(1)).To(HaveLen(7))
+	})
+
+	It("retries failed deliveries", func() {
+		transport.SendReturnsOnCall(0, errors.New("connection refused"))
+		Expect(forwarder.Write([]byte(`{"type":"a"}`))).To(Succeed())
+		Expect(forwarder.Close()).To(Succeed())
+
+		Expect(transport.SendCallCount()).To(Equal(2))
+		Expect(warn.Contents()).To(BeEmpty())
+	})
+
+	It("logs delivery attempts when debugging", func() {
+		debugOut := NewBuffer()
+		forwarder.Debug = &debug.Logger{Out: debugOut}
+		transport.SendReturnsOnCall(0, errors.New("connection refused"))
+		Expect(forwarder.Write([]byte(`{"type":"a"}`))).To(Succeed())
+		Expect(forwarder.Close()).To(Succeed())
+
+		Expect(debugOut).To(Say("sink http://localhost:8080/ingest: read 0 spooled records"))
+		Expect(debugOut).To(Say("sending 1 records, attempt 1"))
+		Expect(debugOut).To(Say("attempt 1 failed: connection refused"))
+		Expect(debugOut).To(Say("sending 1 records, attempt 2"))
+		Expect(debugOut).To(Say("delivered 1 records"))
+	})
+
+	Context("when the sink is down", func() {
+		BeforeEach(func() {
+			transport.SendReturns(errors.New("connection refused"))
+			Expect(forwarder.Write([]byte(`{"type":"a"}`))).To(Succeed())
+			Expect(forwarder.Close()).To(Succeed())
+		})
+
+		It("warns and spools the records", func() {
+			Expect(transport.SendCallCount()).To(Equal(3))
+			Expect(warn).To(Say("warning: sink http://localhost:8080/ingest: failed to deliver 1 records, spooled 1: connection refused"))
+		})
+
+		It("delivers spooled records first once the sink is back", func() {
+			transport.SendReturns(nil)
+			Expect(forwarder.Write([]byte(`{"type":"b"}`))).To(Succeed())
+			Expect(forwarder.Close()).To(Succeed())
+
+			Expect(transport.SendArgsForCall(3)).To(Equal([][]byte{[]byte(`{"type":"a"}`), []byte(`{"type":"b"}`)}))
+
+			Expect(forwarder.Close()).To(Succeed())
+			Expect(transport.SendCallCount()).To(Equal(4))
+		})
+
+		It("bounds the spool by dropping the oldest records", func() {
+			forwarder.SpoolSize = 26
+			Expect(forwarder.Write([]byte(`{"type":"b"}`))).To(Succeed())
+			Expect(forwarder.Write([]byte(`{"type":"c"}`))).To(Succeed())
+			Expect(forwarder.Close()).To(Succeed())
+			Expect(warn).To(Say("failed to deliver 3 records, spooled 2"))
+
+			transport.SendReturns(nil)
+			Expect(forwarder.Close()).To(Succeed())
+			Expect(transport.SendArgsForCall(transport.SendCallCount() - 1)).To(Equal([][]byte{[]byte(`{"type":"b"}`), []byte(`{"type":"c"}`)}))
+		})
+	})
+})
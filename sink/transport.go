@@ -0,0 +1,125 @@
+package sink
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+//go:generate counterfeiter Transport
+type Transport interface {
+	Send(records [][]byte) error
+}
+
+const transportTimeout = 5 * time.Second
+
+// HTTP posts batches of records as JSON lines.
+type HTTP struct {
+	URL    string
+	Client *http.Client
+}
+
+func (h *HTTP) Send(records [][]byte) error {
+	body := bytes.Join(records, []byte("\n"))
+	body = append(body, '\n')
+
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: transportTimeout}
+	}
+	response, err := client.Post(h.URL, "application/x-ndjson", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return nil
+}
+
+// Unix writes batches of records as JSON lines to a Unix domain socket.
+type Unix struct {
+	Path string
+}
+
+func (u *Unix) Send(records [][]byte) error {
+	conn, err := net.DialTimeout("unix", u.Path, transportTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(transportTimeout)); err != nil { // !branch-not-tested
+		return err
+	}
+	body := bytes.Join(records, []byte("\n"))
+	body = append(body, '\n')
+	_, err = conn.Write(body)
+	return err
+}
+
+// Syslog sends each record as an RFC 5424 message over UDP.
+type Syslog struct {
+	Address string
+}
+
+// facility user, severity informational
+const syslogPriority = 1*8 + 6
+
+func (s *Syslog) Send(records [][]byte) error {
+	conn, err := net.DialTimeout("udp", s.Address, transportTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	hostname, err := os.Hostname()
+	if err != nil { // !branch-not-tested
+		hostname = "-"
+	}
+	for _, record := range records {
+		message := fmt.Sprintf("<%d>1 %s %s mrlog %d - - %s",
+			syslogPriority,
+			time.Now().UTC().Format(time.RFC3339Nano),
+			hostname,
+			os.Getpid(),
+			record)
+		if _, err := conn.Write([]byte(message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewTransport returns the transport for a sink URL such as
+// http://localhost:8080/ingest, unix:///run/mrl.sock or syslog://localhost:514
+func NewTransport(sinkURL string) (Transport, error) {
+	parsed, err := url.Parse(sinkURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink %q: %w", sinkURL, err)
+	}
+
+	switch parsed.Scheme {
+	case "http", "https":
+		return &HTTP{URL: sinkURL}, nil
+	case "unix":
+		if parsed.Path == "" {
+			return nil, fmt.Errorf("invalid sink %q: missing socket path", sinkURL)
+		}
+		return &Unix{Path: parsed.Path}, nil
+	case "syslog":
+		address := parsed.Host
+		if parsed.Port() == "" {
+			address = net.JoinHostPort(parsed.Hostname(), "514")
+		}
+		return &Syslog{Address: address}, nil
+	default:
+		return nil, fmt.Errorf("invalid sink %q: unsupported scheme %q", sinkURL, parsed.Scheme)
+	}
+}
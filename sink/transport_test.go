@@ -0,0 +1,118 @@
+package sink_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/cf-platform-eng/mrlog/sink"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transport", func() {
+	records := [][]byte{[]byte(`{"type":"a"}`), []byte(`{"type":"b"}`)}
+
+	Describe("NewTransport", func() {
+		It("supports http, unix and syslog sinks", func() {
+			transport, err := sink.NewTransport("http://localhost:8080/ingest")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transport).To(Equal(&sink.HTTP{URL: "http://localhost:8080/ingest"}))
+
+			transport, err = sink.NewTransport("unix:///run/mrl.sock")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transport).To(Equal(&sink.Unix{Path: "/run/mrl.sock"}))
+
+			transport, err = sink.NewTransport("syslog://localhost")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transport).To(Equal(&sink.Syslog{Address: "localhost:514"}))
+		})
+
+		It("rejects unsupported schemes", func() {
+			_, err := sink.NewTransport("ftp://localhost")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`unsupported scheme "ftp"`))
+		})
+	})
+
+	Describe("HTTP", func() {
+		var (
+			server *httptest.Server
+			status int
+			body   []byte
+		)
+
+		BeforeEach(func() {
+			status = http.StatusAccepted
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(status)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("posts records as JSON lines", func() {
+			Expect((&sink.HTTP{URL: server.URL}).Send(records)).To(Succeed())
+			Expect(string(body)).To(Equal("{\"type\":\"a\"}\n{\"type\":\"b\"}\n"))
+		})
+
+		It("fails on error responses", func() {
+			status = http.StatusServiceUnavailable
+			err := (&sink.HTTP{URL: server.URL}).Send(records)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("503"))
+		})
+	})
+
+	Describe("Unix", func() {
+		It("writes records as JSON lines", func() {
+			dir, err := os.MkdirTemp("", "mrlog-unix")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "mrl.sock")
+			listener, err := net.Listen("unix", path)
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+
+			lines := make(chan string, 2)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+
+			Expect((&sink.Unix{Path: path}).Send(records)).To(Succeed())
+			Eventually(lines).Should(Receive(Equal(`{"type":"a"}`)))
+			Eventually(lines).Should(Receive(Equal(`{"type":"b"}`)))
+		})
+	})
+
+	Describe("Syslog", func() {
+		It("sends a message per record", func() {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+
+			Expect((&sink.Syslog{Address: conn.LocalAddr().String()}).Send(records)).To(Succeed())
+
+			buffer := make([]byte, 1024)
+			n, _, err := conn.ReadFrom(buffer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(buffer[:n])).To(MatchRegexp(`^<14>1 \S+ \S+ mrlog \d+ - - \{"type":"a"\}$`))
+		})
+	})
+})
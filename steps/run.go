@@ -0,0 +1,204 @@
+package steps
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/clock"
+	"github.com/cf-platform-eng/mrlog/debug"
+	"github.com/cf-platform-eng/mrlog/exec"
+	"github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/section"
+	"github.com/cf-platform-eng/mrlog/trace"
+
+	"github.com/fatih/color"
+)
+
+type RunOpt struct {
+	Name         string `long:"name" description:"name of the section around the steps, by default the step file's name or its base name"`
+	NoColor      bool   `long:"no-color" env:"MRLOG_NO_COLOR" description:"do not use colors"`
+	ShellOptions string `long:"shell-options" env:"MRLOG_SHELL_OPTIONS" description:"options for the shell running the step commands, by default -e -o pipefail for bash and -e for /bin/sh"`
+	ShellPath    string `long:"shell-path" env:"MRLOG_SHELL" description:"shell running the step commands, by default bash, or /bin/sh without bash"`
+
+	Printer *mrl.Printer
+	Clock   clock.Clock
+	Exec    exec.Exec
+	Tracer  *trace.Tracer
+	Debug   *debug.Logger
+}
+
+// outcome is how a step ended, for the summary.
+type outcome struct {
+	name     string
+	status   string
+	result   int
+	attempts int
+	duration time.Duration
+}
+
+func (opts *RunOpt) section(sectionType string, name string, result int) section.SectionOpt {
+	return section.SectionOpt{
+		Section: section.Section{
+			Type:   sectionType,
+			Name:   name,
+			Result: result,
+		},
+		Printer: opts.Printer,
+		Clock:   opts.Clock,
+	}
+}
+
+// runStep runs a step as a section, retrying it when it fails, and returns
+// how its last attempt ended and the number of attempts.
+func (opts *RunOpt) runStep(step Step) (*section.SectionOpt, int, error) {
+	output := opts.Printer.Output()
+	for attempt := 1; ; attempt++ {
+		last := attempt > step.Retries
+		stepSection := &section.SectionOpt{
+			Section: section.Section{
+				Type:         "section",
+				Name:         step.Name,
+				Shell:        step.Command,
+				ShellOptions: opts.ShellOptions,
+				ShellPath:    opts.ShellPath,
+				OnSuccess:    step.OnSuccess,
+				OnFailure:    step.OnFailure,
+				Timeout:      step.Timeout,
+				Env:          step.Environment(),
+				AllowFailure: last && step.ContinueOnError,
+			},
+			Printer: opts.Printer,
+			Clock:   opts.Clock,
+			Exec:    opts.Exec,
+			Tracer:  opts.Tracer,
+			Debug:   opts.Debug,
+		}
+		err := stepSection.Execute(nil)
+		var sectionError *section.SectionError
+		if err != nil && !errors.As(err, &sectionError) {
+			return nil, attempt, err
+		}
+
+		if stepSection.Result == 0 || last {
+			return stepSection, attempt, nil
+		}
+		fmt.Fprintf(output, "Retrying step '%s', attempt %d of %d\n", step.Name, attempt+1, step.Retries+1)
+	}
+}
+
+// abort ends the section around the steps when a step could not be run, so
+// that the log has no section left open, and returns err.
+func (opts *RunOpt) abort(name string, err error) error {
+	end := opts.section("end", name, -1)
+	if endErr := end.Execute(nil); endErr != nil {
+		opts.Debug.Printf("run '%s': failed to end the section: %s", name, endErr)
+	}
+	return err
+}
+
+func (opts *RunOpt) Execute(args []string) error {
+	if len(args) != 1 {
+		return errors.New("the run command requires a step file 'run <steps.yml>'")
+	}
+	file, err := Load(args[0])
+	if err != nil {
+		return err
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = file.Name
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+	}
+
+	if opts.NoColor {
+		color.NoColor = true
+	}
+
+	start := opts.section("start", name, 0)
+	if err := start.Execute(nil); err != nil {
+		return err
+	}
+
+	output := opts.Printer.Output()
+	result := 0
+	var outcomes []outcome
+	for _, step := range file.Steps {
+		failed := result != 0
+		reason := ""
+		if step.Condition == ConditionSuccess && failed {
+			reason = "an earlier step failed"
+		} else if step.Condition == ConditionFailure && !failed {
+			reason = "no earlier step failed"
+		}
+		if reason != "" {
+			opts.Debug.Printf("run '%s': skipping step '%s' with condition %s", name, step.Name, step.Condition)
+			skip := &section.SectionOpt{
+				Section: section.Section{
+					Type:   "skip",
+					Name:   step.Name,
+					Reason: reason,
+				},
+				Printer: opts.Printer,
+				Clock:   opts.Clock,
+			}
+			if err := skip.Execute(nil); err != nil {
+				return opts.abort(name, err)
+			}
+			outcomes = append(outcomes, outcome{name: step.Name, status: mrl.StatusSkipped})
+			continue
+		}
+
+		started := opts.Clock.Now()
+		ended, attempts, err := opts.runStep(step)
+		if err != nil {
+			return opts.abort(name, err)
+		}
+		outcomes = append(outcomes, outcome{
+			name:     step.Name,
+			status:   ended.Status,
+			result:   ended.Result,
+			attempts: attempts,
+			duration: opts.Clock.Now().Sub(started),
+		})
+		if ended.Status != mrl.StatusPassed && ended.Status != mrl.StatusWarning && result == 0 {
+			result = ended.Result
+		}
+	}
+
+	writeSummary(output, outcomes)
+
+	end := opts.section("end", name, result)
+	if err := end.Execute(nil); err != nil {
+		return err
+	}
+	if result != 0 {
+		// returning a SectionError to propagate the result as the exit code
+		return &section.SectionError{Retval: result, Err: fmt.Errorf("a step failed with %d", result)}
+	}
+	return nil
+}
+
+func writeSummary(output io.Writer, outcomes []outcome) {
+	fmt.Fprintln(output, "Summary:")
+	table := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	for _, o := range outcomes {
+		if o.status == mrl.StatusSkipped {
+			fmt.Fprintf(table, "  %s\t%s\n", o.name, o.status)
+			continue
+		}
+		details := fmt.Sprintf("result %d in %s", o.result, o.duration.Round(time.Millisecond))
+		if o.attempts > 1 {
+			details += fmt.Sprintf(" after %d attempts", o.attempts)
+		}
+		fmt.Fprintf(table, "  %s\t%s\t%s\n", o.name, o.status, details)
+	}
+	table.Flush()
+}
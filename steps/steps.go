@@ -0,0 +1,106 @@
+package steps
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/section"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConditionSuccess runs a step when no earlier step has failed
+	ConditionSuccess = "success"
+	// ConditionFailure runs a step only when an earlier step has failed
+	ConditionFailure = "failure"
+	// ConditionAlways runs a step whether or not earlier steps failed
+	ConditionAlways = "always"
+)
+
+// Step is one section of a step file.
+type Step struct {
+	Name            string            `yaml:"name"`
+	Command         string            `yaml:"command"`
+	OnSuccess       string            `yaml:"on-success"`
+	OnFailure       string            `yaml:"on-failure"`
+	Timeout         time.Duration     `yaml:"timeout"`
+	Retries         int               `yaml:"retries"`
+	ContinueOnError bool              `yaml:"continue-on-error"`
+	Env             map[string]string `yaml:"env"`
+	Condition       string            `yaml:"condition"`
+}
+
+// File is an ordered list of steps, run as sections within a section.
+type File struct {
+	Name  string `yaml:"name"`
+	Steps []Step `yaml:"steps"`
+}
+
+// Environment returns the step's variables as sorted KEY=VALUE pairs.
+func (s Step) Environment() []string {
+	var env []string
+	for key, value := range s.Env {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+func Load(path string) (*File, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read step file: %w", err)
+	}
+	return Parse(path, contents)
+}
+
+// Parse reads a step file, rejecting unknown keys and incomplete steps.
+func Parse(path string, contents []byte) (*File, error) {
+	file := &File{}
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid step file %s: %w", path, err)
+	}
+	if len(file.Steps) == 0 {
+		return nil, fmt.Errorf("invalid step file %s: no steps", path)
+	}
+
+	names := map[string]bool{}
+	for i := range file.Steps {
+		step := &file.Steps[i]
+		if step.Name == "" {
+			return nil, fmt.Errorf("invalid step file %s: step %d has no name", path, i+1)
+		}
+		if names[step.Name] {
+			return nil, fmt.Errorf("invalid step file %s: duplicate step name %s", path, step.Name)
+		}
+		names[step.Name] = true
+		if step.Command == "" {
+			return nil, fmt.Errorf("invalid step file %s: step %s has no command", path, step.Name)
+		}
+		if err := section.ValidateMessage(step.OnSuccess); err != nil {
+			return nil, fmt.Errorf("invalid step file %s: step %s has an invalid on-success message: %w", path, step.Name, err)
+		}
+		if err := section.ValidateMessage(step.OnFailure); err != nil {
+			return nil, fmt.Errorf("invalid step file %s: step %s has an invalid on-failure message: %w", path, step.Name, err)
+		}
+		if step.Retries < 0 {
+			return nil, fmt.Errorf("invalid step file %s: step %s has negative retries", path, step.Name)
+		}
+		switch step.Condition {
+		case "":
+			step.Condition = ConditionSuccess
+		case ConditionSuccess, ConditionFailure, ConditionAlways:
+		default:
+			return nil, fmt.Errorf("invalid step file %s: step %s has condition %s, expected success, failure or always", path, step.Name, step.Condition)
+		}
+	}
+	return file, nil
+}
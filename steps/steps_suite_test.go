@@ -0,0 +1,13 @@
+package steps_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSteps(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Steps Suite")
+}
@@ -0,0 +1,273 @@
+package steps_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/clock/clockfakes"
+	mrlexec "github.com/cf-platform-eng/mrlog/exec"
+	"github.com/cf-platform-eng/mrlog/exec/execfakes"
+	"github.com/cf-platform-eng/mrlog/mrl"
+	"github.com/cf-platform-eng/mrlog/section"
+	"github.com/cf-platform-eng/mrlog/steps"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Steps", func() {
+	Describe("Parse", func() {
+		It("reads the steps in order", func() {
+			file, err := steps.Parse("steps.yml", []byte(`
+name: pipeline
+steps:
+  - name: unit
+    command: make units
+    on-success: units passed
+    on-failure: units failed
+    timeout: 10m
+    retries: 2
+    continue-on-error: true
+    env:
+      B: two
+      A: one
+  - name: notify
+    command: ./notify.sh
+    condition: failure
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.Name).To(Equal("pipeline"))
+			Expect(file.Steps).To(HaveLen(2))
+			Expect(file.Steps[0].Command).To(Equal("make units"))
+			Expect(file.Steps[0].Timeout).To(Equal(10 * time.Minute))
+			Expect(file.Steps[0].Retries).To(Equal(2))
+			Expect(file.Steps[0].ContinueOnError).To(BeTrue())
+			Expect(file.Steps[0].Condition).To(Equal(steps.ConditionSuccess))
+			Expect(file.Steps[0].Environment()).To(Equal([]string{"A=one", "B=two"}))
+			Expect(file.Steps[1].Condition).To(Equal(steps.ConditionFailure))
+		})
+
+		It("rejects invalid step files", func() {
+			invalid := map[string]string{
+				"name: pipeline": "invalid step file steps.yml: no steps",
+				"steps:\n  - name: unit\n    command: make\n    retry: 2":                      "field retry not found",
+				"steps:\n  - command: make":                                                    "step 1 has no name",
+				"steps:\n  - name: unit":                                                       "step unit has no command",
+				"steps:\n  - name: unit\n    command: make\n  - name: unit\n    command: make": "duplicate step name unit",
+				"steps:\n  - name: unit\n    command: make\n    retries: -1":                   "step unit has negative retries",
+				"steps:\n  - name: unit\n    command: make\n    condition: sometimes":          "step unit has condition sometimes, expected success, failure or always",
+				"steps:\n  - name: unit\n    command: make\n    on-success: '{{.Nope'":         "step unit has an invalid on-success message",
+				"steps:\n  - name: unit\n    command: make\n    on-failure: '{{.Nope}}'":       "step unit has an invalid on-failure message",
+			}
+			for contents, message := range invalid {
+				_, err := steps.Parse("steps.yml", []byte(contents))
+				Expect(err).To(HaveOccurred(), contents)
+				Expect(err.Error()).To(ContainSubstring(message))
+			}
+		})
+	})
+
+	Describe("RunOpt", func() {
+		var (
+			out     *Buffer
+			dir     string
+			context *steps.RunOpt
+			results map[string][]error
+			ran     []string
+			cmds    []*execfakes.FakeCmd
+		)
+
+		exitError := func(code int) error {
+			err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+			Expect(err).To(HaveOccurred())
+			return err
+		}
+
+		writeSteps := func(contents string) string {
+			path := filepath.Join(dir, "steps.yml")
+			Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+			return path
+		}
+
+		BeforeEach(func() {
+			var err error
+			dir, err = os.MkdirTemp("", "mrlog-steps")
+			Expect(err).NotTo(HaveOccurred())
+
+			out = NewBuffer()
+			clock := &clockfakes.FakeClock{}
+			clock.NowReturns(time.Date(1973, 11, 29, 10, 15, 01, 00, time.UTC))
+			results = map[string][]error{}
+			ran = nil
+			cmds = nil
+			fakeExec := &execfakes.FakeExec{}
+			fakeExec.CommandStub = func(command string, args ...string) mrlexec.Cmd {
+				script := args[len(args)-1]
+				cmd := &execfakes.FakeCmd{}
+				cmds = append(cmds, cmd)
+				cmd.RunStub = func() error {
+					ran = append(ran, script)
+					fmt.Fprintf(cmd.SetOutputArgsForCall(0), "running %s\n", script)
+					if len(results[script]) == 0 {
+						return nil
+					}
+					err := results[script][0]
+					results[script] = results[script][1:]
+					return err
+				}
+				return cmd
+			}
+
+			context = &steps.RunOpt{
+				ShellOptions: "-e",
+				Printer:      &mrl.Printer{Out: out, Format: mrl.FormatHuman},
+				Clock:        clock,
+				Exec:         fakeExec,
+			}
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("runs each step as a section within a section", func() {
+			path := writeSteps(`
+steps:
+  - name: build
+    command: make build
+    on-success: built
+  - name: unit
+    command: make units
+    timeout: 1m
+    env:
+      GOFLAGS: -mod=mod
+`)
+			Expect(context.Execute([]string{path})).To(Succeed())
+			Expect(ran).To(Equal([]string{"make build", "make units"}))
+			Expect(out).To(Say("section-start: 'steps'\n"))
+			Expect(out).To(Say("section-start: 'build'\nrunning make build\nsection-end: 'build' result: 0 message: 'built'\n\n"))
+			Expect(out).To(Say("section-start: 'unit'\nrunning make units\nsection-end: 'unit' result: 0\n\n"))
+			Expect(out).To(Say("Summary:\n  build  passed  result 0 in 0s\n  unit   passed  result 0 in 0s\n"))
+			Expect(out).To(Say("section-end: 'steps' result: 0\n\n"))
+		})
+
+		It("runs the step commands with the shell and the step's environment", func() {
+			fakeExec := context.Exec.(*execfakes.FakeExec)
+			path := writeSteps("steps:\n  - name: unit\n    command: make units\n    env:\n      GOFLAGS: -mod=mod\n")
+			Expect(context.Execute([]string{path})).To(Succeed())
+
+			command, args := fakeExec.CommandArgsForCall(0)
+			Expect(append([]string{command}, args...)).To(Equal(section.ShellCommand(section.DefaultShell(), "-e", "make units")))
+			Expect(cmds[0].SetEnvArgsForCall(0)).To(ContainElement("GOFLAGS=-mod=mod"))
+		})
+
+		It("runs the step commands with the shell given by --shell-path", func() {
+			fakeExec := context.Exec.(*execfakes.FakeExec)
+			context.ShellPath = "/usr/bin/zsh"
+			path := writeSteps("steps:\n  - name: unit\n    command: make units\n")
+			Expect(context.Execute([]string{path})).To(Succeed())
+
+			command, args := fakeExec.CommandArgsForCall(0)
+			Expect(append([]string{command}, args...)).To(Equal([]string{"/usr/bin/zsh", "-e", "-c", "make units"}))
+		})
+
+		It("uses the name in the step file, or --name", func() {
+			path := writeSteps("name: pipeline\nsteps:\n  - name: unit\n    command: make units\n")
+			Expect(context.Execute([]string{path})).To(Succeed())
+			Expect(out).To(Say("section-start: 'pipeline'\n"))
+
+			context.Name = "checks"
+			Expect(context.Execute([]string{path})).To(Succeed())
+			Expect(out).To(Say("section-start: 'checks'\n"))
+		})
+
+		It("retries a failing step", func() {
+			results["./flaky.sh"] = []error{exitError(1), exitError(1)}
+			path := writeSteps("steps:\n  - name: flaky\n    command: ./flaky.sh\n    retries: 2\n")
+			Expect(context.Execute([]string{path})).To(Succeed())
+			Expect(ran).To(HaveLen(3))
+			Expect(out).To(Say("section-end: 'flaky' result: 1"))
+			Expect(out).To(Say("Retrying step 'flaky', attempt 2 of 3\n"))
+			Expect(out).To(Say("Retrying step 'flaky', attempt 3 of 3\n"))
+			Expect(out).To(Say("section-end: 'flaky' result: 0"))
+			Expect(out).To(Say("flaky  passed  result 0 in 0s after 3 attempts\n"))
+		})
+
+		It("only allows the last attempt of a step that may fail to fail", func() {
+			results["./flaky.sh"] = []error{exitError(1), exitError(1)}
+			path := writeSteps("steps:\n  - name: flaky\n    command: ./flaky.sh\n    retries: 1\n    continue-on-error: true\n")
+			Expect(context.Execute([]string{path})).To(Succeed())
+			Expect(out).To(Say("section-end: 'flaky' result: 1\n"))
+			Expect(out).To(Say("section-end: 'flaky' result: 1 status: warning\n"))
+			Expect(out).To(Say("flaky  warning  result 1 in 0s after 2 attempts\n"))
+		})
+
+		It("skips the remaining steps after a failure and fails with its result", func() {
+			results["make units"] = []error{exitError(2)}
+			path := writeSteps(`
+steps:
+  - name: unit
+    command: make units
+  - name: deploy
+    command: ./deploy.sh
+  - name: cleanup
+    command: ./cleanup.sh
+    condition: always
+  - name: notify
+    command: ./notify.sh
+    condition: failure
+`)
+			err := context.Execute([]string{path})
+			var sectionError *section.SectionError
+			Expect(errors.As(err, &sectionError)).To(BeTrue())
+			Expect(sectionError.Retval).To(Equal(2))
+
+			Expect(ran).To(Equal([]string{"make units", "./cleanup.sh", "./notify.sh"}))
+			Expect(out).To(Say("section-skip: 'deploy' reason: 'an earlier step failed'\n\n"))
+			Expect(out).To(Say("  unit     failed  result 2 in 0s\n  deploy   skipped\n  cleanup  passed  result 0 in 0s\n  notify   passed  result 0 in 0s\n"))
+			Expect(out).To(Say("section-end: 'steps' result: 2\n\n"))
+		})
+
+		It("continues after a step that may fail", func() {
+			results["make lint"] = []error{exitError(3)}
+			path := writeSteps(`
+steps:
+  - name: lint
+    command: make lint
+    continue-on-error: true
+  - name: notify
+    command: ./notify.sh
+    condition: failure
+  - name: unit
+    command: make units
+`)
+			Expect(context.Execute([]string{path})).To(Succeed())
+			Expect(ran).To(Equal([]string{"make lint", "make units"}))
+			Expect(out).To(Say("section-end: 'lint' result: 3 status: warning"))
+			Expect(out).To(Say("section-skip: 'notify' reason: 'no earlier step failed'\n\n"))
+			Expect(out).To(Say("  lint    warning  result 3 in 0s\n"))
+			Expect(out).To(Say("section-end: 'steps' result: 0\n\n"))
+		})
+
+		It("ends the section around the steps when a step cannot be run", func() {
+			path := writeSteps("steps:\n  - name: unit\n    command: make\n    env:\n      \"\": value\n")
+			Expect(context.Execute([]string{path})).To(MatchError("invalid --env =value: expected KEY=VALUE"))
+			Expect(out).To(Say("section-start: 'steps'\n"))
+			Expect(string(out.Contents())).To(HaveSuffix("section-end: 'steps' result: -1\n\n"))
+		})
+
+		It("requires a step file", func() {
+			Expect(context.Execute([]string{})).To(MatchError("the run command requires a step file 'run <steps.yml>'"))
+		})
+
+		It("reports step files that cannot be read", func() {
+			err := context.Execute([]string{filepath.Join(dir, "missing.yml")})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("failed to read step file:"))
+		})
+	})
+})
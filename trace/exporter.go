@@ -0,0 +1,200 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	spanKindInternal = 1
+	statusCodeOK     = 1
+	statusCodeError  = 2
+)
+
+// Exporter sends spans to an OpenTelemetry collector using OTLP/HTTP with
+// JSON encoding.
+type Exporter struct {
+	Endpoint    string
+	ServiceName string
+	Version     string
+	Resource    map[string]interface{}
+	Client      *http.Client
+}
+
+// TracesURL returns the traces URL for an OTLP endpoint, following the
+// OTEL_EXPORTER_OTLP_ENDPOINT convention of appending /v1/traces.
+func TracesURL(endpoint string) string {
+	if strings.HasSuffix(endpoint, "/v1/traces") {
+		return endpoint
+	}
+	return strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Name         string          `json:"name"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Events            []otlpEvent     `json:"events,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func value(v interface{}) otlpValue {
+	switch typed := v.(type) {
+	case string:
+		return otlpValue{StringValue: &typed}
+	case int:
+		s := strconv.Itoa(typed)
+		return otlpValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(typed, 10)
+		return otlpValue{IntValue: &s}
+	case float64:
+		return otlpValue{DoubleValue: &typed}
+	case bool:
+		return otlpValue{BoolValue: &typed}
+	default:
+		s := fmt.Sprint(typed)
+		return otlpValue{StringValue: &s}
+	}
+}
+
+func attributes(values map[string]interface{}) []otlpAttribute {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var result []otlpAttribute
+	for _, key := range keys {
+		result = append(result, otlpAttribute{Key: key, Value: value(values[key])})
+	}
+	return result
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func (e *Exporter) request(spans []*Span) otlpRequest {
+	resource := map[string]interface{}{
+		"service.name": e.ServiceName,
+	}
+	for key, v := range e.Resource {
+		resource[key] = v
+	}
+
+	scopeSpans := otlpScopeSpans{
+		Scope: otlpScope{Name: "mrlog", Version: e.Version},
+	}
+	for _, span := range spans {
+		converted := otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentSpanID,
+			Name:              span.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: unixNano(span.Start),
+			EndTimeUnixNano:   unixNano(span.End),
+			Attributes:        attributes(span.Attributes),
+			Status:            otlpStatus{Code: statusCodeOK},
+		}
+		if span.Failed {
+			converted.Status = otlpStatus{Code: statusCodeError, Message: span.Message}
+		}
+		for _, event := range span.Events {
+			converted.Events = append(converted.Events, otlpEvent{
+				TimeUnixNano: unixNano(event.Time),
+				Name:         event.Name,
+				Attributes:   attributes(event.Attributes),
+			})
+		}
+		scopeSpans.Spans = append(scopeSpans.Spans, converted)
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource:   otlpResource{Attributes: attributes(resource)},
+			ScopeSpans: []otlpScopeSpans{scopeSpans},
+		}},
+	}
+}
+
+func (e *Exporter) Export(spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil { // !branch-not-tested
+		return err
+	}
+
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	response, err := client.Post(TracesURL(e.Endpoint), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+const TraceParentEnv = "TRACEPARENT"
+
+var traceParentRE = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]interface{}
+}
+
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	// Flags are the W3C trace flags, such as whether the trace is sampled
+	Flags      string
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{}
+	Events     []Event
+	Failed     bool
+	Message    string
+}
+
+// TraceParent returns the W3C traceparent header value identifying the span,
+// for propagation to child processes, with the trace flags of its parent.
+func (s *Span) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%s", s.TraceID, s.SpanID, s.Flags)
+}
+
+// Tracer starts spans as children of Parent, a W3C traceparent value usually
+// taken from the TRACEPARENT environment variable, and exports them when they
+// end. Export failures are only reported as warnings.
+type Tracer struct {
+	Exporter *Exporter
+	Parent   string
+	Warn     io.Writer
+}
+
+// Enabled reports whether spans are exported. A nil Tracer is disabled.
+func (t *Tracer) Enabled() bool {
+	return t != nil && t.Exporter != nil
+}
+
+func randomID(bytes int) string {
+	id := make([]byte, bytes)
+	if _, err := rand.Read(id); err != nil { // !branch-not-tested
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
+
+func (t *Tracer) Start(name string, start time.Time) *Span {
+	span := &Span{
+		SpanID:     randomID(8),
+		Name:       name,
+		Start:      start,
+		Attributes: map[string]interface{}{},
+	}
+
+	if matches := traceParentRE.FindStringSubmatch(t.Parent); matches != nil {
+		span.TraceID = matches[1]
+		span.ParentSpanID = matches[2]
+		span.Flags = matches[3]
+	} else {
+		// a new trace is sampled
+		span.TraceID = randomID(16)
+		span.Flags = "01"
+	}
+	return span
+}
+
+func (t *Tracer) End(span *Span, end time.Time) {
+	span.End = end
+	if err := t.Exporter.Export([]*Span{span}); err != nil && t.Warn != nil {
+		fmt.Fprintf(t.Warn, "warning: failed to export span '%s': %s\n", span.Name, err)
+	}
+}
@@ -0,0 +1,131 @@
+package trace_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/cf-platform-eng/mrlog/trace"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Tracer", func() {
+	var (
+		collector *httptest.Server
+		status    int
+		path      string
+		requests  []map[string]interface{}
+		warn      *Buffer
+		tracer    *trace.Tracer
+		start     time.Time
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		requests = nil
+		collector = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			body, _ := io.ReadAll(r.Body)
+			request := map[string]interface{}{}
+			Expect(json.Unmarshal(body, &request)).To(Succeed())
+			requests = append(requests, request)
+			w.WriteHeader(status)
+		}))
+
+		warn = NewBuffer()
+		tracer = &trace.Tracer{
+			Exporter: &trace.Exporter{
+				Endpoint:    collector.URL,
+				ServiceName: "mrlog",
+			},
+			Warn: warn,
+		}
+		start = time.Date(1973, 11, 29, 10, 15, 01, 00, time.UTC)
+	})
+
+	AfterEach(func() {
+		collector.Close()
+	})
+
+	It("is disabled without an exporter", func() {
+		Expect((&trace.Tracer{}).Enabled()).To(BeFalse())
+		var nilTracer *trace.Tracer
+		Expect(nilTracer.Enabled()).To(BeFalse())
+		Expect(tracer.Enabled()).To(BeTrue())
+	})
+
+	It("starts a new trace without a parent", func() {
+		span := tracer.Start("install", start)
+		Expect(span.TraceID).To(MatchRegexp(`^[0-9a-f]{32}$`))
+		Expect(span.SpanID).To(MatchRegexp(`^[0-9a-f]{16}$`))
+		Expect(span.ParentSpanID).To(BeEmpty())
+		Expect(span.TraceParent()).To(Equal("00-" + span.TraceID + "-" + span.SpanID + "-01"))
+	})
+
+	It("continues the trace of the parent", func() {
+		tracer.Parent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+		span := tracer.Start("install", start)
+		Expect(span.TraceID).To(Equal("0af7651916cd43dd8448eb211c80319c"))
+		Expect(span.ParentSpanID).To(Equal("b7ad6b7169203331"))
+		Expect(span.SpanID).NotTo(Equal("b7ad6b7169203331"))
+		Expect(span.TraceParent()).To(Equal("00-0af7651916cd43dd8448eb211c80319c-" + span.SpanID + "-01"))
+	})
+
+	It("keeps the trace flags of the parent", func() {
+		tracer.Parent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00"
+		span := tracer.Start("install", start)
+		Expect(span.TraceParent()).To(Equal("00-0af7651916cd43dd8448eb211c80319c-" + span.SpanID + "-00"))
+	})
+
+	It("exports ended spans as OTLP JSON", func() {
+		span := tracer.Start("install", start)
+		span.Attributes["process.exit_code"] = 2
+		span.Events = append(span.Events, trace.Event{
+			Name:       "binary dependency",
+			Time:       start,
+			Attributes: map[string]interface{}{"mrlog.dependency.name": "kubectl"},
+		})
+		span.Failed = true
+		span.Message = "install failed"
+		tracer.End(span, start.Add(2*time.Second))
+
+		Expect(path).To(Equal("/v1/traces"))
+		Expect(requests).To(HaveLen(1))
+
+		resourceSpans := requests[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})
+		resource := resourceSpans["resource"].(map[string]interface{})
+		Expect(resource["attributes"]).To(ContainElement(map[string]interface{}{
+			"key":   "service.name",
+			"value": map[string]interface{}{"stringValue": "mrlog"},
+		}))
+
+		exported := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+		Expect(exported["name"]).To(Equal("install"))
+		Expect(exported["traceId"]).To(Equal(span.TraceID))
+		Expect(exported["spanId"]).To(Equal(span.SpanID))
+		Expect(exported["startTimeUnixNano"]).To(Equal("123416101000000000"))
+		Expect(exported["endTimeUnixNano"]).To(Equal("123416103000000000"))
+		Expect(exported["status"]).To(Equal(map[string]interface{}{"code": float64(2), "message": "install failed"}))
+		Expect(exported["attributes"]).To(ContainElement(map[string]interface{}{
+			"key":   "process.exit_code",
+			"value": map[string]interface{}{"intValue": "2"},
+		}))
+		event := exported["events"].([]interface{})[0].(map[string]interface{})
+		Expect(event["name"]).To(Equal("binary dependency"))
+	})
+
+	It("only warns when the export fails", func() {
+		status = http.StatusInternalServerError
+		tracer.End(tracer.Start("install", start), start)
+		Expect(warn).To(Say("warning: failed to export span 'install': unexpected status 500"))
+	})
+
+	It("uses trace endpoints as is", func() {
+		Expect(trace.TracesURL("http://localhost:4318")).To(Equal("http://localhost:4318/v1/traces"))
+		Expect(trace.TracesURL("http://localhost:4318/v1/traces")).To(Equal("http://localhost:4318/v1/traces"))
+	})
+})
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package mrlog
+
+import "github.com/cf-platform-eng/mrlog/mrl"
+
+func addRusage(usage *mrl.Usage, sysUsage interface{}) {}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package mrlog
+
+import (
+	"runtime"
+	"syscall"
+
+	"github.com/cf-platform-eng/mrlog/mrl"
+)
+
+func addRusage(usage *mrl.Usage, sysUsage interface{}) {
+	rusage, ok := sysUsage.(*syscall.Rusage)
+	if !ok {
+		return
+	}
+	// ru_maxrss is in bytes on macOS and in kilobytes elsewhere
+	usage.MaxRSS = int64(rusage.Maxrss)
+	if runtime.GOOS != "darwin" {
+		usage.MaxRSS *= 1024
+	}
+	usage.BlockInput = int64(rusage.Inblock)
+	usage.BlockOutput = int64(rusage.Oublock)
+}